@@ -0,0 +1,70 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backoff
+
+import (
+	"math/rand/v2"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrBudgetExhausted is returned by Next when the number of failed attempts
+// has reached Options.MaxAttempts.
+var ErrBudgetExhausted = errors.New("retry budget exhausted")
+
+// Backoff computes the delay before the next attempt of a failing operation
+// using exponential backoff with jitter. It is not safe for concurrent use.
+type Backoff struct {
+	opts     Options
+	attempts int
+}
+
+func New(opts Options) *Backoff {
+	return &Backoff{opts: opts}
+}
+
+// Next records a failed attempt and returns how long to wait before the next
+// one. It returns ErrBudgetExhausted once no attempts are left.
+func (b *Backoff) Next() (time.Duration, error) {
+	b.attempts++
+	if b.opts.MaxAttempts > 0 && b.attempts >= b.opts.MaxAttempts {
+		return 0, errors.Wrapf(ErrBudgetExhausted, "%d attempts failed", b.attempts)
+	}
+
+	delay := b.opts.MinDelay
+	for i := 1; i < b.attempts && delay < b.opts.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > b.opts.MaxDelay {
+		delay = b.opts.MaxDelay
+	}
+	if b.opts.Jitter > 0 {
+		delay -= time.Duration(rand.Float64() * b.opts.Jitter * float64(delay))
+	}
+	return delay, nil
+}
+
+// Attempts returns the number of failed attempts since the last Reset.
+func (b *Backoff) Attempts() int {
+	return b.attempts
+}
+
+// Reset must be called after a successful attempt.
+func (b *Backoff) Reset() {
+	b.attempts = 0
+}
@@ -0,0 +1,77 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backoff
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff_Next(t *testing.T) {
+	b := New(Options{
+		MinDelay: time.Second,
+		MaxDelay: 5 * time.Second,
+	})
+
+	for _, exp := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		delay, err := b.Next()
+		if assert.Nil(t, err) {
+			assert.Equal(t, exp, delay)
+		}
+	}
+
+	b.Reset()
+	delay, err := b.Next()
+	if assert.Nil(t, err) {
+		assert.Equal(t, time.Second, delay)
+	}
+}
+
+func TestBackoff_Jitter(t *testing.T) {
+	b := New(Options{
+		MinDelay: 10 * time.Second,
+		MaxDelay: 10 * time.Second,
+		Jitter:   0.5,
+	})
+
+	for range 100 {
+		delay, err := b.Next()
+		if assert.Nil(t, err) {
+			assert.True(t, delay > 5*time.Second && delay <= 10*time.Second, "delay %s out of range", delay)
+		}
+	}
+}
+
+func TestBackoff_MaxAttempts(t *testing.T) {
+	b := New(Options{
+		MinDelay:    time.Second,
+		MaxDelay:    time.Second,
+		MaxAttempts: 3,
+	})
+
+	for range 2 {
+		_, err := b.Next()
+		assert.Nil(t, err)
+	}
+
+	_, err := b.Next()
+	assert.True(t, errors.Is(err, ErrBudgetExhausted))
+	assert.Equal(t, 3, b.Attempts())
+}
@@ -0,0 +1,82 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backoff
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+const (
+	MinDelayDefault = time.Second
+	MaxDelayDefault = 2 * time.Minute
+	JitterDefault   = 0.2
+)
+
+type Options struct {
+	// delay before the first retry, it is doubled after every failed attempt
+	MinDelay time.Duration
+
+	// upper bound of the delay between two attempts
+	MaxDelay time.Duration
+
+	// fraction of the delay (0 <= jitter <= 1) that is randomly taken off,
+	// so that multiple unsealers do not retry in lock step
+	Jitter float64
+
+	// number of failed attempts allowed for a single phase before giving up,
+	// 0 means retry forever
+	MaxAttempts int
+}
+
+func NewOptions() *Options {
+	return &Options{
+		MinDelay: MinDelayDefault,
+		MaxDelay: MaxDelayDefault,
+		Jitter:   JitterDefault,
+	}
+}
+
+func (o *Options) AddFlags(fs *pflag.FlagSet) {
+	fs.DurationVar(&o.MinDelay, "backoff.min-delay", o.MinDelay, "Delay before retrying a failed phase (init, unseal, configure) for the first time, it is doubled after every failed attempt")
+	fs.DurationVar(&o.MaxDelay, "backoff.max-delay", o.MaxDelay, "Maximum delay between two attempts of a failed phase")
+	fs.Float64Var(&o.Jitter, "backoff.jitter", o.Jitter, "Fraction of the delay (between 0 and 1) that is randomly taken off before each retry")
+	fs.IntVar(&o.MaxAttempts, "backoff.max-attempts", o.MaxAttempts, "Number of failed attempts allowed for each phase before the unsealer gives up and exits. 0 means retry forever")
+}
+
+func (o *Options) Validate() []error {
+	var errs []error
+	if o.MinDelay <= 0 {
+		errs = append(errs, errors.New("backoff min delay must be positive"))
+	}
+	if o.MaxDelay < o.MinDelay {
+		errs = append(errs, errors.New("backoff max delay must be greater than or equal to min delay"))
+	}
+	if o.Jitter < 0 || o.Jitter > 1 {
+		errs = append(errs, errors.New("backoff jitter must be between 0 and 1"))
+	}
+	if o.MaxAttempts < 0 {
+		errs = append(errs, errors.New("backoff max attempts must not be negative"))
+	}
+	return errs
+}
+
+func (o *Options) Apply() error {
+	return nil
+}
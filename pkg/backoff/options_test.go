@@ -0,0 +1,69 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backoff
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	aggregator "gomodules.xyz/errors"
+)
+
+func TestOptions_Validate(t *testing.T) {
+	testData := []struct {
+		testName    string
+		opts        *Options
+		expectedErr error
+	}{
+		{
+			"default options, validation successful",
+			NewOptions(),
+			nil,
+		},
+		{
+			"max delay < min delay, validation failed",
+			&Options{
+				MinDelay: time.Minute,
+				MaxDelay: time.Second,
+			},
+			errors.New("backoff max delay must be greater than or equal to min delay"),
+		},
+		{
+			"all invalid, validation failed",
+			&Options{
+				MinDelay:    0,
+				MaxDelay:    -1,
+				Jitter:      2,
+				MaxAttempts: -1,
+			},
+			errors.New("[backoff min delay must be positive, backoff max delay must be greater than or equal to min delay, backoff jitter must be between 0 and 1, backoff max attempts must not be negative]"),
+		},
+	}
+
+	for _, test := range testData {
+		t.Run(test.testName, func(t *testing.T) {
+			errs := test.opts.Validate()
+			if test.expectedErr != nil {
+				assert.EqualError(t, aggregator.NewAggregate(errs), test.expectedErr.Error())
+			} else {
+				assert.Nil(t, errs)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmds
+
+import (
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"kubevault.dev/unsealer/pkg/worker"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	utilerrors "gomodules.xyz/errors"
+)
+
+func NewCmdKeys() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "keys",
+		Short:             "Manage the unseal keys and root tokens stored in the key store",
+		DisableAutoGenTag: true,
+	}
+
+	cmd.AddCommand(newCmdKeysList())
+	cmd.AddCommand(newCmdKeysPrune())
+	cmd.AddCommand(newCmdKeysHistory())
+	cmd.AddCommand(newCmdKeysRestore())
+	return cmd
+}
+
+func printKeys(cmd *cobra.Command, keys []worker.StoredKey) error {
+	tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 8, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "PREFIX\tKIND\tKEY")
+	for _, key := range keys {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\n", key.Prefix, key.Kind, key.ID)
+	}
+	return tw.Flush()
+}
+
+func newCmdKeysList() *cobra.Command {
+	opts := worker.NewWorkerOptions()
+
+	cmd := &cobra.Command{
+		Use:               "list",
+		Short:             "List the unseal keys, root tokens and snapshots stored in the key store, of every key prefix",
+		DisableAutoGenTag: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := opts.LoadConfig(cmd.Flags()); err != nil {
+				return err
+			}
+			if errs := opts.ValidateClients(); errs != nil {
+				return utilerrors.NewAggregate(errs)
+			}
+
+			keys, err := opts.ListKeys(cmd.Context())
+			if err != nil {
+				return err
+			}
+			return printKeys(cmd, keys)
+		},
+	}
+
+	opts.AddFlags(cmd.Flags())
+	return cmd
+}
+
+func newCmdKeysPrune() *cobra.Command {
+	opts := worker.NewWorkerOptions()
+	var prefixes []string
+	dryRun := false
+
+	cmd := &cobra.Command{
+		Use:               "prune",
+		Short:             "Delete the keys of old key prefixes from the key store",
+		DisableAutoGenTag: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := opts.LoadConfig(cmd.Flags()); err != nil {
+				return err
+			}
+			if errs := opts.ValidateClients(); errs != nil {
+				return utilerrors.NewAggregate(errs)
+			}
+			if len(prefixes) == 0 {
+				return errors.New("--prefix is required")
+			}
+
+			keys, err := opts.PruneKeys(cmd.Context(), prefixes, dryRun)
+			if perr := printKeys(cmd, keys); perr != nil && err == nil {
+				err = perr
+			}
+			return err
+		},
+	}
+
+	opts.AddFlags(cmd.Flags())
+	cmd.Flags().StringSliceVar(&prefixes, "prefix", prefixes, "Key prefix whose keys are deleted, as shown by 'keys list'. The key prefix in use can not be pruned")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", dryRun, "Only print the keys that would be deleted")
+	return cmd
+}
+
+func newCmdKeysHistory() *cobra.Command {
+	opts := worker.NewWorkerOptions()
+	key := ""
+
+	cmd := &cobra.Command{
+		Use:               "history",
+		Short:             "List the versions of a key in the key store, the newest first",
+		DisableAutoGenTag: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := opts.LoadConfig(cmd.Flags()); err != nil {
+				return err
+			}
+			if errs := opts.ValidateClients(); errs != nil {
+				return utilerrors.NewAggregate(errs)
+			}
+			if key == "" {
+				return errors.New("--key is required")
+			}
+
+			versions, err := opts.KeyVersions(cmd.Context(), key)
+			if err != nil {
+				return err
+			}
+
+			tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 8, 2, ' ', 0)
+			_, _ = fmt.Fprintln(tw, "VERSION\tTIME\tCURRENT")
+			for _, v := range versions {
+				t := "<unknown>"
+				if !v.Time.IsZero() {
+					t = v.Time.Format(time.RFC3339)
+				}
+				_, _ = fmt.Fprintf(tw, "%s\t%s\t%t\n", v.ID, t, v.Current)
+			}
+			return tw.Flush()
+		},
+	}
+
+	opts.AddFlags(cmd.Flags())
+	cmd.Flags().StringVar(&key, "key", key, "Key to list the versions of, as shown by 'keys list'")
+	return cmd
+}
+
+func newCmdKeysRestore() *cobra.Command {
+	opts := worker.NewWorkerOptions()
+	key := ""
+	version := ""
+
+	cmd := &cobra.Command{
+		Use:               "restore",
+		Short:             "Set a key in the key store to the value of a previous version",
+		DisableAutoGenTag: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := opts.LoadConfig(cmd.Flags()); err != nil {
+				return err
+			}
+			if errs := opts.ValidateClients(); errs != nil {
+				return utilerrors.NewAggregate(errs)
+			}
+			if key == "" || version == "" {
+				return errors.New("--key and --version are required")
+			}
+
+			return opts.RestoreKey(cmd.Context(), key, version)
+		},
+	}
+
+	opts.AddFlags(cmd.Flags())
+	cmd.Flags().StringVar(&key, "key", key, "Key to restore, as shown by 'keys list'")
+	cmd.Flags().StringVar(&version, "version", version, "Version of the key to restore, as shown by 'keys history'")
+	return cmd
+}
@@ -0,0 +1,62 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmds
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"kubevault.dev/unsealer/pkg/vault/unseal"
+	"kubevault.dev/unsealer/pkg/worker"
+
+	"github.com/spf13/cobra"
+	utilerrors "gomodules.xyz/errors"
+)
+
+func NewCmdRekey() *cobra.Command {
+	opts := worker.NewWorkerOptions()
+	rekeyOpts := unseal.NewRekeyOptions()
+
+	cmd := &cobra.Command{
+		Use:               "rekey",
+		Short:             "Rotate the unseal keys of Vault and store the new keys in the key store",
+		DisableAutoGenTag: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := opts.LoadConfig(cmd.Flags()); err != nil {
+				return err
+			}
+
+			errs := opts.ValidateClients()
+			errs = append(errs, rekeyOpts.Validate()...)
+			if errs != nil {
+				return utilerrors.NewAggregate(errs)
+			}
+
+			// the rekey is cancelled before the new keys are stored, after that
+			// it runs to the end
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			return opts.Rekey(ctx, rekeyOpts)
+		},
+	}
+
+	opts.AddFlags(cmd.Flags())
+	rekeyOpts.AddFlags(cmd.Flags())
+	return cmd
+}
@@ -30,6 +30,9 @@ func NewRootCmd() *cobra.Command {
 
 	rootCmd.AddCommand(v.NewCmdVersion())
 	rootCmd.AddCommand(NewCmdRun())
+	rootCmd.AddCommand(NewCmdRekey())
+	rootCmd.AddCommand(NewCmdSnapshot())
+	rootCmd.AddCommand(NewCmdKeys())
 
 	return rootCmd
 }
@@ -17,10 +17,15 @@ limitations under the License.
 package cmds
 
 import (
+	"os"
+	"os/signal"
+	"syscall"
+
 	"kubevault.dev/unsealer/pkg/worker"
 
 	"github.com/spf13/cobra"
 	utilerrors "gomodules.xyz/errors"
+	"gomodules.xyz/logs"
 	v "gomodules.xyz/x/version"
 	"k8s.io/klog/v2"
 )
@@ -35,10 +40,30 @@ func NewCmdRun() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			klog.Infof("Starting operator version %s+%s ...", v.Version.Version, v.Version.CommitHash)
 
+			if err := opts.LoadConfig(cmd.Flags()); err != nil {
+				return err
+			}
+
 			if errs := opts.Validate(); errs != nil {
 				return utilerrors.NewAggregate(errs)
 			}
-			return opts.Run()
+
+			// stop on SIGTERM (e.g. pod deletion) or Ctrl+C, the worker finishes
+			// the action in progress before it returns
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			if opts.OneShot {
+				code, err := opts.RunOnce(ctx)
+				if err != nil {
+					klog.Errorln(err)
+				}
+				// os.Exit skips the deferred calls in main
+				logs.FlushLogs()
+				os.Exit(int(code))
+			}
+
+			return opts.Run(ctx)
 		},
 	}
 
@@ -0,0 +1,104 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmds
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"kubevault.dev/unsealer/pkg/worker"
+
+	"github.com/spf13/cobra"
+	utilerrors "gomodules.xyz/errors"
+)
+
+func NewCmdSnapshot() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "snapshot",
+		Short:             "Manage the raft snapshots stored in the key store",
+		DisableAutoGenTag: true,
+	}
+
+	cmd.AddCommand(newCmdSnapshotList())
+	cmd.AddCommand(newCmdSnapshotRestore())
+	return cmd
+}
+
+func newCmdSnapshotList() *cobra.Command {
+	opts := worker.NewWorkerOptions()
+
+	cmd := &cobra.Command{
+		Use:               "list",
+		Short:             "List the raft snapshots stored in the key store, the newest first",
+		DisableAutoGenTag: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := opts.LoadConfig(cmd.Flags()); err != nil {
+				return err
+			}
+			if errs := opts.ValidateClients(); errs != nil {
+				return utilerrors.NewAggregate(errs)
+			}
+
+			list, err := opts.ListSnapshots(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 8, 2, ' ', 0)
+			_, _ = fmt.Fprintln(tw, "SLOT\tTIME\tSIZE\tSHA256")
+			for _, m := range list {
+				_, _ = fmt.Fprintf(tw, "%d\t%s\t%d\t%s\n", m.Slot, m.Time.Format(time.RFC3339), m.Size, m.SHA256)
+			}
+			return tw.Flush()
+		},
+	}
+
+	opts.AddFlags(cmd.Flags())
+	return cmd
+}
+
+func newCmdSnapshotRestore() *cobra.Command {
+	opts := worker.NewWorkerOptions()
+	slot := -1
+
+	cmd := &cobra.Command{
+		Use:               "restore",
+		Short:             "Restore a raft snapshot stored in the key store to a Vault cluster that is not initialized",
+		DisableAutoGenTag: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := opts.LoadConfig(cmd.Flags()); err != nil {
+				return err
+			}
+			if errs := opts.ValidateClients(); errs != nil {
+				return utilerrors.NewAggregate(errs)
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			return opts.RestoreSnapshot(ctx, slot)
+		},
+	}
+
+	opts.AddFlags(cmd.Flags())
+	cmd.Flags().IntVar(&slot, "slot", slot, "Slot of the snapshot to restore, as shown by 'snapshot list'. The newest snapshot is restored if it is negative")
+	return cmd
+}
@@ -0,0 +1,268 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	APIVersion = "unsealer.kubevault.com/v1alpha1"
+	Kind       = "UnsealerConfiguration"
+
+	// EnvPrefix is the prefix of the environment variables that override the
+	// config file, e.g. VAULT_UNSEALER_AZURE_CLIENT_SECRET for the
+	// azure.client-secret flag
+	EnvPrefix = "VAULT_UNSEALER_"
+)
+
+// Loader sets flags from a config file and the environment.
+//
+// The config file is a YAML or JSON document that holds apiVersion, kind and
+// the flags by name. Nested objects are joined with a dot, so
+//
+//	apiVersion: unsealer.kubevault.com/v1alpha1
+//	kind: UnsealerConfiguration
+//	mode: kubernetes-secret
+//	vault:
+//	  address: https://vault:8200
+//	k8s.secret-name: vault-keys
+//
+// sets --mode, --vault.address and --k8s.secret-name. Lists are joined with a
+// comma.
+type Loader struct {
+	path string
+
+	// values of the flags that were set on the command line
+	cmdline map[string][]string
+}
+
+// NewLoader returns a loader for the config file at path, path may be empty.
+// It must be called after fs is parsed and before Load, since it remembers the
+// flags that were set on the command line.
+func NewLoader(fs *pflag.FlagSet, path string) *Loader {
+	l := &Loader{
+		path:    path,
+		cmdline: map[string][]string{},
+	}
+	fs.Visit(func(f *pflag.Flag) {
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			l.cmdline[f.Name] = sv.GetSlice()
+		} else {
+			l.cmdline[f.Name] = []string{f.Value.String()}
+		}
+	})
+	return l
+}
+
+// Path returns the path of the config file.
+func (l *Loader) Path() string {
+	return l.path
+}
+
+// Load sets the flags in fs. A flag that was set on the command line keeps
+// that value, otherwise it is taken from the environment, then from the
+// config file. Flags that are set nowhere keep their default.
+//
+// fs may be a fresh flag set, e.g. to reload the config file into new options.
+func (l *Loader) Load(fs *pflag.FlagSet) error {
+	values := map[string]string{}
+	if l.path != "" {
+		data, err := os.ReadFile(l.path)
+		if err != nil {
+			return errors.Wrap(err, "failed to read the config file")
+		}
+		if values, err = Parse(data); err != nil {
+			return errors.Wrapf(err, "failed to parse the config file %s", l.path)
+		}
+	}
+
+	var unknown []string
+	for name := range values {
+		if fs.Lookup(name) == nil {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return errors.Errorf("unknown options in the config file %s: %s", l.path, strings.Join(unknown, ", "))
+	}
+
+	var err error
+	fs.VisitAll(func(f *pflag.Flag) {
+		if err != nil {
+			return
+		}
+		if v, ok := l.cmdline[f.Name]; ok {
+			if sv, ok := f.Value.(pflag.SliceValue); ok {
+				err = sv.Replace(v)
+			} else {
+				err = f.Value.Set(v[0])
+			}
+			return
+		}
+
+		source, v := "", ""
+		if ev, ok := os.LookupEnv(EnvName(f.Name)); ok {
+			source, v = "environment variable "+EnvName(f.Name), ev
+		} else if fv, ok := values[f.Name]; ok {
+			source, v = "config file", fv
+		} else {
+			return
+		}
+		if e := fs.Set(f.Name, v); e != nil {
+			err = errors.Wrapf(e, "invalid value for %s from the %s", f.Name, source)
+		}
+	})
+	return err
+}
+
+// Watch calls onChange whenever the content of the config file changes, until
+// ctx is done. The directory of the file is watched, so that a file which is
+// replaced instead of written, as Kubernetes does for mounted ConfigMaps and
+// Secrets, is followed.
+func (l *Loader) Watch(ctx context.Context, onChange func()) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return errors.Wrap(err, "failed to create the config file watcher")
+	}
+	if err := watcher.Add(filepath.Dir(l.path)); err != nil {
+		_ = watcher.Close()
+		return errors.Wrap(err, "failed to watch the config file")
+	}
+
+	last, _ := os.ReadFile(l.path)
+
+	go func() {
+		defer watcher.Close() //nolint:errcheck
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				data, err := os.ReadFile(l.path)
+				if err != nil || bytes.Equal(data, last) {
+					continue
+				}
+				last = data
+				onChange()
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				klog.Errorf("failed to watch the config file with %s", err.Error())
+			}
+		}
+	}()
+	return nil
+}
+
+// EnvName returns the name of the environment variable that overrides a flag.
+func EnvName(flag string) string {
+	return EnvPrefix + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(flag))
+}
+
+// Parse returns the flag values held by a config file.
+func Parse(data []byte) (map[string]string, error) {
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	if v := doc["apiVersion"]; v != APIVersion {
+		return nil, errors.Errorf("unsupported apiVersion %v, expected %s", v, APIVersion)
+	}
+	if k := doc["kind"]; k != Kind {
+		return nil, errors.Errorf("unsupported kind %v, expected %s", k, Kind)
+	}
+	delete(doc, "apiVersion")
+	delete(doc, "kind")
+
+	values := map[string]string{}
+	if err := flatten("", doc, values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func flatten(prefix string, in map[string]interface{}, out map[string]string) error {
+	for k, v := range in {
+		name := k
+		if prefix != "" {
+			name = prefix + "." + k
+		}
+
+		if m, ok := v.(map[string]interface{}); ok {
+			if err := flatten(name, m, out); err != nil {
+				return err
+			}
+			continue
+		}
+
+		s, err := toString(v)
+		if err != nil {
+			return errors.Wrapf(err, "invalid value for %s", name)
+		}
+		if _, ok := out[name]; ok {
+			return errors.Errorf("%s is set more than once", name)
+		}
+		out[name] = s
+	}
+	return nil
+}
+
+func toString(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			s, err := toString(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, s)
+		}
+		return strings.Join(items, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported type %T", v)
+	}
+}
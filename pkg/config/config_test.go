@@ -0,0 +1,219 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+type testOptions struct {
+	Address     string
+	Secret      string
+	RetryPeriod time.Duration
+	Shares      int
+	Enabled     bool
+	Keys        []string
+}
+
+func (o *testOptions) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.Address, "vault.address", o.Address, "")
+	fs.StringVar(&o.Secret, "azure.client-secret", o.Secret, "")
+	fs.DurationVar(&o.RetryPeriod, "retry-period", o.RetryPeriod, "")
+	fs.IntVar(&o.Shares, "secret-shares", o.Shares, "")
+	fs.BoolVar(&o.Enabled, "leader-elect", o.Enabled, "")
+	fs.StringSliceVar(&o.Keys, "pgp-keys", o.Keys, "")
+}
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		testName  string
+		data      string
+		expectErr bool
+		expected  map[string]string
+	}{
+		{
+			testName: "nested yaml",
+			data: `apiVersion: unsealer.kubevault.com/v1alpha1
+kind: UnsealerConfiguration
+mode: kubernetes-secret
+secret-shares: 5
+vault:
+  address: https://vault:8200
+leader-elect: true
+leader-elect.lease-name: unsealer
+pgp-keys: [a, b]
+`,
+			expected: map[string]string{
+				"mode":                    "kubernetes-secret",
+				"secret-shares":           "5",
+				"vault.address":           "https://vault:8200",
+				"leader-elect":            "true",
+				"leader-elect.lease-name": "unsealer",
+				"pgp-keys":                "a,b",
+			},
+		},
+		{
+			testName: "json",
+			data:     `{"apiVersion": "unsealer.kubevault.com/v1alpha1", "kind": "UnsealerConfiguration", "retry-period": "5s"}`,
+			expected: map[string]string{"retry-period": "5s"},
+		},
+		{
+			testName:  "unknown apiVersion",
+			data:      "apiVersion: v1\nkind: UnsealerConfiguration\n",
+			expectErr: true,
+		},
+		{
+			testName:  "missing kind",
+			data:      "apiVersion: unsealer.kubevault.com/v1alpha1\n",
+			expectErr: true,
+		},
+		{
+			testName: "set twice",
+			data: `apiVersion: unsealer.kubevault.com/v1alpha1
+kind: UnsealerConfiguration
+vault.address: a
+vault:
+  address: b
+`,
+			expectErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.testName, func(t *testing.T) {
+			values, err := Parse([]byte(c.data))
+			if c.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(values, c.expected) {
+				t.Errorf("expected %v, got %v", c.expected, values)
+			}
+		})
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	if got := EnvName("azure.client-secret"); got != "VAULT_UNSEALER_AZURE_CLIENT_SECRET" {
+		t.Errorf("unexpected env name %s", got)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, path, `apiVersion: unsealer.kubevault.com/v1alpha1
+kind: UnsealerConfiguration
+vault.address: https://file:8200
+azure.client-secret: from-file
+retry-period: 30s
+secret-shares: 3
+pgp-keys: [c, d]
+`)
+	t.Setenv(EnvName("azure.client-secret"), "from-env")
+	t.Setenv(EnvName("retry-period"), "20s")
+
+	o := &testOptions{RetryPeriod: 10 * time.Second, Shares: 5}
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	o.AddFlags(fs)
+	if err := fs.Parse([]string{"--retry-period=1m", "--pgp-keys=a,b"}); err != nil {
+		t.Fatal(err)
+	}
+
+	l := NewLoader(fs, path)
+	if err := l.Load(fs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := &testOptions{
+		Address:     "https://file:8200",
+		Secret:      "from-env",
+		RetryPeriod: time.Minute,
+		Shares:      3,
+		Keys:        []string{"a", "b"},
+	}
+	if !reflect.DeepEqual(o, expected) {
+		t.Errorf("expected %+v, got %+v", expected, o)
+	}
+
+	// reloading into fresh options keeps the command line values
+	writeFile(t, path, `apiVersion: unsealer.kubevault.com/v1alpha1
+kind: UnsealerConfiguration
+vault.address: https://changed:8200
+`)
+	n := &testOptions{RetryPeriod: 10 * time.Second, Shares: 5}
+	nfs := pflag.NewFlagSet("reload", pflag.ContinueOnError)
+	n.AddFlags(nfs)
+	if err := l.Load(nfs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected = &testOptions{
+		Address:     "https://changed:8200",
+		Secret:      "from-env",
+		RetryPeriod: time.Minute,
+		Shares:      5,
+		Keys:        []string{"a", "b"},
+	}
+	if !reflect.DeepEqual(n, expected) {
+		t.Errorf("expected %+v, got %+v", expected, n)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	cases := []struct {
+		testName string
+		data     string
+	}{
+		{
+			testName: "unknown option",
+			data:     "apiVersion: unsealer.kubevault.com/v1alpha1\nkind: UnsealerConfiguration\nvault.adress: x\n",
+		},
+		{
+			testName: "invalid value",
+			data:     "apiVersion: unsealer.kubevault.com/v1alpha1\nkind: UnsealerConfiguration\nsecret-shares: many\n",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.testName, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			writeFile(t, path, c.data)
+
+			fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+			(&testOptions{}).AddFlags(fs)
+			if err := NewLoader(fs, path).Load(fs); err == nil {
+				t.Errorf("expected error, got none")
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
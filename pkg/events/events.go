@@ -0,0 +1,97 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events
+
+import (
+	"context"
+
+	core "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// Event reasons are part of the interface of the unsealer, tools may match on
+// them. Do not change existing values.
+const (
+	ReasonVaultInitialized    = "VaultInitialized"
+	ReasonVaultInitFailed     = "VaultInitFailed"
+	ReasonVaultUnsealed       = "VaultUnsealed"
+	ReasonVaultUnsealFailed   = "VaultUnsealFailed"
+	ReasonVaultConfigured     = "VaultConfigured"
+	ReasonVaultConfigFailed   = "VaultConfigureFailed"
+	ReasonKeyStoreUnreachable = "KeyStoreUnreachable"
+	ReasonKeysAlreadyExist    = "KeysAlreadyExist"
+	ReasonVaultJoined         = "VaultJoined"
+	ReasonVaultJoinFailed     = "VaultJoinFailed"
+	ReasonSnapshotTaken       = "SnapshotTaken"
+	ReasonSnapshotFailed      = "SnapshotFailed"
+)
+
+const component = "vault-unsealer"
+
+// Recorder records events against a single object.
+type Recorder interface {
+	Event(eventType, reason, message string)
+	Eventf(eventType, reason, messageFmt string, args ...any)
+}
+
+type recorder struct {
+	recorder record.EventRecorder
+	ref      *core.ObjectReference
+}
+
+var _ Recorder = &recorder{}
+
+// NewRecorder returns a Recorder that sends events to the kubernetes api
+// until the context is cancelled.
+func NewRecorder(ctx context.Context, client kubernetes.Interface, opts *Options) Recorder {
+	broadcaster := record.NewBroadcaster(record.WithContext(ctx))
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{
+		Interface: client.CoreV1().Events(opts.ObjectNamespace),
+	})
+
+	return &recorder{
+		recorder: broadcaster.NewRecorder(scheme.Scheme, core.EventSource{Component: component}),
+		ref: &core.ObjectReference{
+			APIVersion: opts.apiVersion(),
+			Kind:       opts.ObjectKind,
+			Name:       opts.ObjectName,
+			Namespace:  opts.ObjectNamespace,
+		},
+	}
+}
+
+func (r *recorder) Event(eventType, reason, message string) {
+	r.recorder.Event(r.ref, eventType, reason, message)
+}
+
+func (r *recorder) Eventf(eventType, reason, messageFmt string, args ...any) {
+	r.recorder.Eventf(r.ref, eventType, reason, messageFmt, args...)
+}
+
+type nopRecorder struct{}
+
+// NewNopRecorder returns a Recorder that drops all events.
+func NewNopRecorder() Recorder {
+	return nopRecorder{}
+}
+
+func (nopRecorder) Event(eventType, reason, message string) {}
+
+func (nopRecorder) Eventf(eventType, reason, messageFmt string, args ...any) {}
@@ -0,0 +1,89 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events
+
+import (
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	meta_util "kmodules.xyz/client-go/meta"
+)
+
+type Options struct {
+	// record kubernetes events for lifecycle transitions
+	Enabled bool
+
+	// the object events are recorded against,
+	// e.g. the vault pod or its owning StatefulSet
+	ObjectAPIVersion string
+	ObjectKind       string
+	ObjectName       string
+	ObjectNamespace  string
+}
+
+func NewOptions() *Options {
+	return &Options{
+		ObjectKind:      "Pod",
+		ObjectName:      meta_util.PodName(),
+		ObjectNamespace: meta_util.PodNamespace(),
+	}
+}
+
+func (o *Options) AddFlags(fs *pflag.FlagSet) {
+	fs.BoolVar(&o.Enabled, "record-events", o.Enabled, "Record Kubernetes events when vault is initialized, unsealed or configured, or when the key store fails")
+	fs.StringVar(&o.ObjectAPIVersion, "events.object-api-version", o.ObjectAPIVersion, "API version of the object events are recorded against. Can be omitted for Pod and StatefulSet")
+	fs.StringVar(&o.ObjectKind, "events.object-kind", o.ObjectKind, "Kind of the object events are recorded against, e.g. Pod or StatefulSet")
+	fs.StringVar(&o.ObjectName, "events.object-name", o.ObjectName, "Name of the object events are recorded against. Defaults to the name of the unsealer pod")
+	fs.StringVar(&o.ObjectNamespace, "events.object-namespace", o.ObjectNamespace, "Namespace of the object events are recorded against")
+}
+
+func (o *Options) Validate() []error {
+	if !o.Enabled {
+		return nil
+	}
+
+	var errs []error
+	if o.apiVersion() == "" {
+		errs = append(errs, errors.New("events.object-api-version must be non empty for kinds other than Pod and StatefulSet"))
+	}
+	if o.ObjectKind == "" {
+		errs = append(errs, errors.New("events.object-kind must be non empty"))
+	}
+	if o.ObjectName == "" {
+		errs = append(errs, errors.New("events.object-name must be non empty"))
+	}
+	if o.ObjectNamespace == "" {
+		errs = append(errs, errors.New("events.object-namespace must be non empty"))
+	}
+	return errs
+}
+
+func (o *Options) Apply() error {
+	return nil
+}
+
+func (o *Options) apiVersion() string {
+	if o.ObjectAPIVersion != "" {
+		return o.ObjectAPIVersion
+	}
+	switch o.ObjectKind {
+	case "Pod":
+		return "v1"
+	case "StatefulSet":
+		return "apps/v1"
+	}
+	return ""
+}
@@ -0,0 +1,79 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	aggregator "gomodules.xyz/errors"
+)
+
+func TestOptions_Validate(t *testing.T) {
+	testData := []struct {
+		testName    string
+		opts        *Options
+		expectedErr error
+	}{
+		{
+			"events disabled, validation successful",
+			&Options{},
+			nil,
+		},
+		{
+			"statefulset without api version, validation successful",
+			&Options{
+				Enabled:         true,
+				ObjectKind:      "StatefulSet",
+				ObjectName:      "vault",
+				ObjectNamespace: "demo",
+			},
+			nil,
+		},
+		{
+			"unknown kind without api version, validation failed",
+			&Options{
+				Enabled:         true,
+				ObjectKind:      "VaultServer",
+				ObjectName:      "vault",
+				ObjectNamespace: "demo",
+			},
+			errors.New("events.object-api-version must be non empty for kinds other than Pod and StatefulSet"),
+		},
+		{
+			"name is empty, validation failed",
+			&Options{
+				Enabled:         true,
+				ObjectKind:      "Pod",
+				ObjectNamespace: "demo",
+			},
+			errors.New("events.object-name must be non empty"),
+		},
+	}
+
+	for _, test := range testData {
+		t.Run(test.testName, func(t *testing.T) {
+			errs := test.opts.Validate()
+			if test.expectedErr != nil {
+				assert.EqualError(t, aggregator.NewAggregate(errs), test.expectedErr.Error())
+			} else {
+				assert.Nil(t, errs)
+			}
+		})
+	}
+}
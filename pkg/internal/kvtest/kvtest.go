@@ -0,0 +1,139 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package kvtest is an in memory key store, for tests of the code that uses a
+// key store. It keeps every value set for a key as a version.
+package kvtest
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"kubevault.dev/unsealer/pkg/kv"
+)
+
+// Store is an implementation of the kv.Service interface that keeps the
+// values in memory. The versions of a key are numbered from 1.
+type Store struct {
+	mu      sync.Mutex
+	history map[string][][]byte
+}
+
+var _ kv.Service = &Store{}
+
+// New returns a store that holds values, every value as version 1
+func New(values map[string][]byte) *Store {
+	s := &Store{history: map[string][][]byte{}}
+	for key, value := range values {
+		s.history[key] = [][]byte{value}
+	}
+	return s
+}
+
+// Values returns a copy of the current value of every key
+func (s *Store) Values() map[string][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	values := make(map[string][]byte, len(s.history))
+	for key, history := range s.history {
+		values[key] = history[len(history)-1]
+	}
+	return values
+}
+
+func (s *Store) Set(ctx context.Context, key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.history[key] = append(s.history[key], value)
+	return nil
+}
+
+func (s *Store) Get(ctx context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	history, ok := s.history[key]
+	if !ok {
+		return nil, kv.NewNotFoundError("key %s not found", key)
+	}
+	return history[len(history)-1], nil
+}
+
+func (s *Store) Create(ctx context.Context, key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.history[key]; ok {
+		return kv.NewExistsError("key %s exists", key)
+	}
+	s.history[key] = [][]byte{value}
+	return nil
+}
+
+// Delete removes the key and its versions
+func (s *Store) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.history, key)
+	return nil
+}
+
+func (s *Store) List(ctx context.Context, prefix string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var keys []string
+	for key := range s.history {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (s *Store) Versions(ctx context.Context, key string) ([]kv.Version, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	history, ok := s.history[key]
+	if !ok {
+		return nil, kv.NewNotFoundError("key %s not found", key)
+	}
+	var versions []kv.Version
+	for i := len(history); i > 0; i-- {
+		versions = append(versions, kv.Version{ID: strconv.Itoa(i), Current: i == len(history)})
+	}
+	return versions, nil
+}
+
+func (s *Store) GetVersion(ctx context.Context, key, version string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	history := s.history[key]
+	i, err := strconv.Atoi(version)
+	if err != nil || i < 1 || i > len(history) {
+		return nil, kv.NewNotFoundError("version %s of key %s not found", version, key)
+	}
+	return history[i-1], nil
+}
+
+func (s *Store) CheckWriteAccess(ctx context.Context) error {
+	return nil
+}
+
+func (s *Store) Test(ctx context.Context, key string) error {
+	return nil
+}
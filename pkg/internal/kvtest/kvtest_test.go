@@ -0,0 +1,56 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kvtest
+
+import (
+	"context"
+	"testing"
+
+	"kubevault.dev/unsealer/pkg/kv"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	s := New(map[string][]byte{"vault-root-token": []byte("root")})
+
+	require.NoError(t, s.Set(ctx, "vault-unseal-key-0", []byte("a")))
+	require.NoError(t, s.Set(ctx, "vault-unseal-key-0", []byte("b")))
+	assert.IsType(t, &kv.ExistsError{}, s.Create(ctx, "vault-unseal-key-0", []byte("c")))
+
+	v, err := s.Get(ctx, "vault-unseal-key-0")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("b"), v)
+
+	versions, err := s.Versions(ctx, "vault-unseal-key-0")
+	require.NoError(t, err)
+	assert.Equal(t, []kv.Version{{ID: "2", Current: true}, {ID: "1"}}, versions)
+	v, err = s.GetVersion(ctx, "vault-unseal-key-0", "1")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("a"), v)
+
+	keys, err := s.List(ctx, "vault-")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"vault-root-token", "vault-unseal-key-0"}, keys)
+
+	require.NoError(t, s.Delete(ctx, "vault-unseal-key-0"))
+	_, err = s.Versions(ctx, "vault-unseal-key-0")
+	assert.IsType(t, &kv.NotFoundError{}, err)
+	assert.Equal(t, map[string][]byte{"vault-root-token": []byte("root")}, s.Values())
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package aws_kms
 
 import (
+	"context"
 	"fmt"
 
 	"kubevault.dev/unsealer/pkg/kv"
@@ -27,16 +28,17 @@ import (
 	"github.com/aws/aws-sdk-go/service/kms"
 )
 
+// awsKMS is an implementation of the kv.Encrypter interface, that encrypts
+// and decrypts data using an AWS KMS key.
 type awsKMS struct {
-	store      kv.Service
 	kmsService *kms.KMS
 
 	kmsID string
 }
 
-var _ kv.Service = &awsKMS{}
+var _ kv.Encrypter = &awsKMS{}
 
-func NewWithSession(sess *session.Session, store kv.Service, kmsID string) (kv.Service, error) {
+func NewEncrypterWithSession(sess *session.Session, kmsID string) (kv.Encrypter, error) {
 	if kmsID == "" {
 		return nil, fmt.Errorf("invalid kmsID specified: '%s'", kmsID)
 	}
@@ -51,23 +53,43 @@ func NewWithSession(sess *session.Session, store kv.Service, kmsID string) (kv.S
 	}
 
 	return &awsKMS{
-		store:      store,
 		kmsService: ksmService,
 		kmsID:      kmsID,
 	}, nil
 }
 
-func New(store kv.Service, kmsID string) (kv.Service, error) {
+func NewEncrypter(kmsID string) (kv.Encrypter, error) {
 	sess, err := session.NewSession()
 	if err != nil {
 		return nil, err
 	}
 
-	return NewWithSession(sess, store, kmsID)
+	return NewEncrypterWithSession(sess, kmsID)
+}
+
+// NewWithSession returns a store that encrypts the values of store with the
+// AWS KMS key
+func NewWithSession(sess *session.Session, store kv.Service, kmsID string) (kv.Service, error) {
+	encrypter, err := NewEncrypterWithSession(sess, kmsID)
+	if err != nil {
+		return nil, err
+	}
+
+	return kv.WithEncryption(store, encrypter), nil
+}
+
+// New returns a store that encrypts the values of store with the AWS KMS key
+func New(store kv.Service, kmsID string) (kv.Service, error) {
+	encrypter, err := NewEncrypter(kmsID)
+	if err != nil {
+		return nil, err
+	}
+
+	return kv.WithEncryption(store, encrypter), nil
 }
 
-func (a *awsKMS) decrypt(cipherText []byte) ([]byte, error) {
-	out, err := a.kmsService.Decrypt(&kms.DecryptInput{
+func (a *awsKMS) Decrypt(ctx context.Context, cipherText []byte) ([]byte, error) {
+	out, err := a.kmsService.DecryptWithContext(ctx, &kms.DecryptInput{
 		CiphertextBlob: cipherText,
 		EncryptionContext: map[string]*string{
 			"Tool": aws.String("vault-unsealer"),
@@ -77,17 +99,8 @@ func (a *awsKMS) decrypt(cipherText []byte) ([]byte, error) {
 	return out.Plaintext, err
 }
 
-func (a *awsKMS) Get(key string) ([]byte, error) {
-	cipherText, err := a.store.Get(key)
-	if err != nil {
-		return nil, err
-	}
-
-	return a.decrypt(cipherText)
-}
-
-func (a *awsKMS) encrypt(plainText []byte) ([]byte, error) {
-	out, err := a.kmsService.Encrypt(&kms.EncryptInput{
+func (a *awsKMS) Encrypt(ctx context.Context, plainText []byte) ([]byte, error) {
+	out, err := a.kmsService.EncryptWithContext(ctx, &kms.EncryptInput{
 		KeyId:     aws.String(a.kmsID),
 		Plaintext: plainText,
 		EncryptionContext: map[string]*string{
@@ -97,41 +110,3 @@ func (a *awsKMS) encrypt(plainText []byte) ([]byte, error) {
 	})
 	return out.CiphertextBlob, err
 }
-
-func (a *awsKMS) Set(key string, val []byte) error {
-	cipherText, err := a.encrypt(val)
-	if err != nil {
-		return err
-	}
-
-	return a.store.Set(key, cipherText)
-}
-
-func (a *awsKMS) CheckWriteAccess() error {
-	return a.store.CheckWriteAccess()
-}
-
-func (g *awsKMS) Test(key string) error {
-	inputString := "test"
-
-	err := g.store.Test(key)
-	if err != nil {
-		return fmt.Errorf("test of backend store failed: %s", err.Error())
-	}
-
-	cipherText, err := g.encrypt([]byte(inputString))
-	if err != nil {
-		return err
-	}
-
-	plainText, err := g.decrypt(cipherText)
-	if err != nil {
-		return err
-	}
-
-	if string(plainText) != inputString {
-		return fmt.Errorf("encryped and decryped text doesn't match: exp: '%v', act: '%v'", inputString, string(plainText))
-	}
-
-	return nil
-}
@@ -17,45 +17,14 @@ limitations under the License.
 package aws_kms
 
 import (
+	"context"
 	"os"
 	"testing"
 
+	"kubevault.dev/unsealer/pkg/internal/kvtest"
 	"kubevault.dev/unsealer/pkg/kv"
 )
 
-type fakeKV struct {
-	Values map[string]*[]byte
-}
-
-func NewFakeKV() *fakeKV {
-	return &fakeKV{
-		Values: map[string]*[]byte{},
-	}
-}
-
-func (f *fakeKV) Test(key string) error {
-	return nil
-}
-
-func (f *fakeKV) CheckWriteAccess() error {
-	return nil
-}
-
-func (f *fakeKV) Set(key string, data []byte) error {
-	f.Values[key] = &data
-	return nil
-}
-
-func (f *fakeKV) Get(key string) ([]byte, error) {
-	out, ok := f.Values[key]
-	if !ok {
-		return []byte{}, kv.NewNotFoundError("key '%s' not found", key)
-	}
-	return *out, nil
-}
-
-var _ kv.Service = &fakeKV{}
-
 func TestAWSIntegration(t *testing.T) {
 	keyID := os.Getenv("AWS_KMS_KEY_ID")
 	region := os.Getenv("AWS_REGION")
@@ -68,31 +37,31 @@ func TestAWSIntegration(t *testing.T) {
 		t.Skip("Skip AWS integration tests: not environment variable 'AWS_REGION' specified")
 	}
 
-	fakeKV := NewFakeKV()
+	store := kvtest.New(nil)
 
 	payloadKey := "test123"
 	payloadValue := "payload123"
 
-	a, err := New(fakeKV, keyID)
+	a, err := New(store, keyID)
 	if err != nil {
 		t.Errorf("Unexpected error creating KMS kv: %s", err)
 	}
 
-	err = a.Set(payloadKey, []byte(payloadValue))
+	err = a.Set(context.Background(), payloadKey, []byte(payloadValue))
 	if err != nil {
 		t.Errorf("Unexpected error storing value in KMS kv: %s", err)
 	}
 
-	value, ok := fakeKV.Values[payloadKey]
+	value, ok := store.Values()[payloadKey]
 	if !ok {
 		t.Errorf("Nothing stored in backend storage")
 	}
 
-	if act := string(*value); act == payloadValue {
+	if act := string(value); act == payloadValue {
 		t.Errorf("Value stored in backend storage is unencrypted: %s", act)
 	}
 
-	out, err := a.Get("test123")
+	out, err := a.Get(context.Background(), "test123")
 	if err != nil {
 		t.Errorf("Unexpected error storing value in KMS kv: %s", err)
 	}
@@ -101,7 +70,7 @@ func TestAWSIntegration(t *testing.T) {
 		t.Errorf("Unexpected decrypt output: exp=%s act=%s", exp, act)
 	}
 
-	_, err = a.Get("not-found")
+	_, err = a.Get(context.Background(), "not-found")
 	if _, ok := err.(*kv.NotFoundError); !ok {
 		t.Errorf("Expected an kv.NotFoundError for a non existing key")
 	}
@@ -0,0 +1,41 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws_kms
+
+import (
+	"kubevault.dev/unsealer/pkg/kv"
+	"kubevault.dev/unsealer/pkg/kv/backend"
+)
+
+// Provider is the name of the options of the aws backends
+const Provider = "aws"
+
+func init() {
+	backend.RegisterOptions(Provider, func() backend.Options { return NewOptions() })
+	backend.RegisterEncryption(&backend.Encryption{
+		Name:        "awskms",
+		Description: "encryption using AWS KMS",
+		Validate: func(opts backend.OptionSet) []error {
+			return opts[Provider].(*Options).Validate()
+		},
+		NewEncrypter: func(opts backend.OptionSet) (kv.Encrypter, error) {
+			return NewEncrypter(opts[Provider].(*Options).KmsKeyID)
+		},
+		// kms encrypts at most 4 KiB
+		SnapshotChunkSize: 2 << 10,
+	})
+}
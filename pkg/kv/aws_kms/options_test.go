@@ -24,18 +24,6 @@ import (
 	aggregator "gomodules.xyz/errors"
 )
 
-func getValidationErrorForFlagsNotProvided() []error {
-	errs := make([]error, 0, 1)
-	errs = append(errs, errors.New("--aws.kms-key-id or --aws.use-secure-string must be defined"))
-	return errs
-}
-
-func getValidationErrorForBothFlagProvided() []error {
-	errs := make([]error, 0, 1)
-	errs = append(errs, errors.New("--aws.kms-key-id and --aws.use-secure-string both are defined, but only one of them is needed"))
-	return errs
-}
-
 func TestOptions_Validate(t *testing.T) {
 	testData := []struct {
 		testName    string
@@ -46,37 +34,15 @@ func TestOptions_Validate(t *testing.T) {
 			"aws key id is provided, validation successful",
 			&Options{
 				"test-key",
-				false,
-				"",
 			},
 			nil,
 		},
 		{
-			"aws key and useSecureString is provided, error expected",
+			"aws key id not provided, validation failed",
 			&Options{
-				"test-key",
-				true,
-				"",
-			},
-			aggregator.NewAggregate(getValidationErrorForBothFlagProvided()),
-		},
-		{
-			"useSecureString is provided",
-			&Options{
-				"",
-				true,
-				"",
-			},
-			nil,
-		},
-		{
-			"aws key id and use secure string not provided, validation failed",
-			&Options{
-				"",
-				false,
 				"",
 			},
-			aggregator.NewAggregate(getValidationErrorForFlagsNotProvided()),
+			aggregator.NewAggregate([]error{errors.New("--aws.kms-key-id must be defined")}),
 		},
 	}
 
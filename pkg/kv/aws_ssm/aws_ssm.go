@@ -17,13 +17,18 @@ limitations under the License.
 package aws_ssm
 
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 
 	"kubevault.dev/unsealer/pkg/kv"
 	"kubevault.dev/unsealer/pkg/kv/util"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/pkg/errors"
@@ -64,7 +69,7 @@ func New(useSecureString bool, keyPrefix string) (*awsSSM, error) {
 	return NewWithSession(sess, useSecureString, keyPrefix)
 }
 
-func (a *awsSSM) Get(key string) ([]byte, error) {
+func (a *awsSSM) Get(ctx context.Context, key string) ([]byte, error) {
 	req := &ssm.GetParametersInput{
 		Names: []*string{
 			aws.String(a.name(key)),
@@ -72,7 +77,7 @@ func (a *awsSSM) Get(key string) ([]byte, error) {
 		WithDecryption: aws.Bool(a.useSecureString),
 	}
 
-	out, err := a.ssmService.GetParameters(req)
+	out, err := a.ssmService.GetParametersWithContext(ctx, req)
 	if err != nil {
 		return []byte{}, err
 	}
@@ -84,15 +89,86 @@ func (a *awsSSM) Get(key string) ([]byte, error) {
 	return base64.StdEncoding.DecodeString(*out.Parameters[0].Value)
 }
 
+// Versions returns the versions kept in the parameter history, ssm keeps the
+// last 100 versions of a parameter.
+func (a *awsSSM) Versions(ctx context.Context, key string) ([]kv.Version, error) {
+	req := &ssm.GetParameterHistoryInput{
+		Name:           aws.String(a.name(key)),
+		WithDecryption: aws.Bool(false),
+	}
+
+	var history []*ssm.ParameterHistory
+	err := a.ssmService.GetParameterHistoryPagesWithContext(ctx, req, func(out *ssm.GetParameterHistoryOutput, lastPage bool) bool {
+		history = append(history, out.Parameters...)
+		return true
+	})
+	if isNotFound(err) {
+		return nil, kv.NewNotFoundError("key '%s' not found", key)
+	} else if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(history, func(i, j int) bool {
+		return aws.Int64Value(history[i].Version) > aws.Int64Value(history[j].Version)
+	})
+	versions := make([]kv.Version, 0, len(history))
+	for i, h := range history {
+		versions = append(versions, kv.Version{
+			ID:      strconv.FormatInt(aws.Int64Value(h.Version), 10),
+			Time:    aws.TimeValue(h.LastModifiedDate),
+			Current: i == 0,
+		})
+	}
+	return versions, nil
+}
+
+func (a *awsSSM) GetVersion(ctx context.Context, key, version string) ([]byte, error) {
+	if _, err := strconv.ParseInt(version, 10, 64); err != nil {
+		return nil, errors.Errorf("invalid version %q of key '%s'", version, key)
+	}
+
+	out, err := a.ssmService.GetParameterWithContext(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(a.name(key) + ":" + version),
+		WithDecryption: aws.Bool(a.useSecureString),
+	})
+	if isNotFound(err) {
+		return nil, kv.NewNotFoundError("version %s of key '%s' not found", version, key)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return base64.StdEncoding.DecodeString(aws.StringValue(out.Parameter.Value))
+}
+
+func isNotFound(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	return ok && (aerr.Code() == ssm.ErrCodeParameterNotFound || aerr.Code() == ssm.ErrCodeParameterVersionNotFound)
+}
+
 func (a *awsSSM) name(key string) string {
 	return fmt.Sprintf("%s%s", a.keyPrefix, key)
 }
 
-func (a *awsSSM) Set(key string, val []byte) error {
+func (a *awsSSM) Set(ctx context.Context, key string, val []byte) error {
+	_, err := a.ssmService.PutParameterWithContext(ctx, a.putRequest(key, val, true))
+	return err
+}
+
+// Create puts the parameter without overwriting it, ssm rejects the request
+// if the parameter exists.
+func (a *awsSSM) Create(ctx context.Context, key string, val []byte) error {
+	_, err := a.ssmService.PutParameterWithContext(ctx, a.putRequest(key, val, false))
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == ssm.ErrCodeParameterAlreadyExists {
+		return kv.NewExistsError("key '%s' exists", key)
+	}
+	return err
+}
+
+func (a *awsSSM) putRequest(key string, val []byte, overwrite bool) *ssm.PutParameterInput {
 	req := &ssm.PutParameterInput{
 		Description: aws.String("vault-unsealer"),
 		Name:        aws.String(a.name(key)),
-		Overwrite:   aws.Bool(true),
+		Overwrite:   aws.Bool(overwrite),
 		Value:       aws.String(base64.StdEncoding.EncodeToString(val)),
 	}
 
@@ -101,28 +177,24 @@ func (a *awsSSM) Set(key string, val []byte) error {
 	} else {
 		req.Type = aws.String("String")
 	}
-
-	_, err := a.ssmService.PutParameter(req)
-	return err
+	return req
 }
 
-func (a *awsSSM) CheckWriteAccess() error {
+func (a *awsSSM) CheckWriteAccess(ctx context.Context) error {
 	key := "vault-unsealer-dummy-file"
 	val := "read write access check"
 
-	err := a.Set(key, []byte(val))
+	err := a.Set(ctx, key, []byte(val))
 	if err != nil {
 		return errors.Wrap(err, "failed to write test file")
 	}
 
-	_, err = a.Get(key)
+	_, err = a.Get(ctx, key)
 	if err != nil {
 		return errors.Wrap(err, "failed to get test file")
 	}
 
-	_, err = a.ssmService.DeleteParameter(&ssm.DeleteParameterInput{
-		Name: aws.String(a.name(key)),
-	})
+	err = a.Delete(ctx, key)
 	if err != nil {
 		return errors.Wrap(err, "failed to delete test file")
 	}
@@ -130,7 +202,50 @@ func (a *awsSSM) CheckWriteAccess() error {
 	return nil
 }
 
-func (g *awsSSM) Test(key string) error {
+func (a *awsSSM) Delete(ctx context.Context, key string) error {
+	_, err := a.ssmService.DeleteParameterWithContext(ctx, &ssm.DeleteParameterInput{
+		Name: aws.String(a.name(key)),
+	})
+	if isNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func (a *awsSSM) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	err := a.ssmService.DescribeParametersPagesWithContext(ctx, a.listRequest(prefix), func(out *ssm.DescribeParametersOutput, lastPage bool) bool {
+		for _, p := range out.Parameters {
+			keys = append(keys, strings.TrimPrefix(aws.StringValue(p.Name), a.keyPrefix))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// listRequest describes the parameters whose name begins with prefix. SSM
+// rejects empty filter values, so every parameter is described if neither
+// prefix nor the key prefix is set.
+func (a *awsSSM) listRequest(prefix string) *ssm.DescribeParametersInput {
+	req := &ssm.DescribeParametersInput{}
+	if name := a.name(prefix); name != "" {
+		req.ParameterFilters = []*ssm.ParameterStringFilter{
+			{
+				Key:    aws.String("Name"),
+				Option: aws.String("BeginsWith"),
+				Values: []*string{aws.String(name)},
+			},
+		}
+	}
+	return req
+}
+
+func (g *awsSSM) Test(ctx context.Context, key string) error {
 	// TODO: Implement a test if a Set is likely to work, AWS doesn't seemt to provide a dry-run on the parameter store
 	return nil
 }
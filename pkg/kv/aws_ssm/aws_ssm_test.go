@@ -17,12 +17,52 @@ limitations under the License.
 package aws_ssm
 
 import (
+	"context"
 	"os"
 	"testing"
 
 	"kubevault.dev/unsealer/pkg/kv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/stretchr/testify/assert"
 )
 
+func TestListRequest(t *testing.T) {
+	testData := []struct {
+		testName  string
+		keyPrefix string
+		prefix    string
+		filters   []*ssm.ParameterStringFilter
+	}{
+		{
+			"no prefix, every parameter is described",
+			"",
+			"",
+			nil,
+		},
+		{
+			"key prefix only",
+			"vault/",
+			"",
+			[]*ssm.ParameterStringFilter{{Key: aws.String("Name"), Option: aws.String("BeginsWith"), Values: []*string{aws.String("vault/")}}},
+		},
+		{
+			"key prefix and prefix",
+			"vault/",
+			"old",
+			[]*ssm.ParameterStringFilter{{Key: aws.String("Name"), Option: aws.String("BeginsWith"), Values: []*string{aws.String("vault/old")}}},
+		},
+	}
+
+	for _, test := range testData {
+		t.Run(test.testName, func(t *testing.T) {
+			a := &awsSSM{keyPrefix: test.keyPrefix}
+			assert.Equal(t, test.filters, a.listRequest(test.prefix).ParameterFilters)
+		})
+	}
+}
+
 func TestAWSIntegration(t *testing.T) {
 	region := os.Getenv("AWS_REGION")
 
@@ -39,28 +79,28 @@ func TestAWSIntegration(t *testing.T) {
 	}
 
 	// graceful set (in case it's already existing)
-	err = a.Set(payloadKey, []byte(payloadValue))
+	err = a.Set(context.Background(), payloadKey, []byte(payloadValue))
 	if err != nil {
 		t.Errorf("Unexpected error storing value in SSM kv: %s", err)
 	}
 
 	// this should also work and overwrite a key
-	err = a.Set(payloadKey, []byte(payloadValue))
+	err = a.Set(context.Background(), payloadKey, []byte(payloadValue))
 	if err != nil {
 		t.Errorf("Unexpected error storing value in SSM kv: %s", err)
 	}
 
-	_, err = a.Get(payloadKey)
+	_, err = a.Get(context.Background(), payloadKey)
 	if _, ok := err.(*kv.NotFoundError); !ok {
 		t.Errorf("Expected an kv.NotFoundError for a non existing key")
 	}
 
-	err = a.Set(payloadKey, []byte(payloadValue))
+	err = a.Set(context.Background(), payloadKey, []byte(payloadValue))
 	if err != nil {
 		t.Errorf("Unexpected error storing value in SSM kv: %s", err)
 	}
 
-	out, err := a.Get("test123")
+	out, err := a.Get(context.Background(), "test123")
 	if err != nil {
 		t.Errorf("Unexpected error storing value in SSM kv: %s", err)
 	}
@@ -0,0 +1,80 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws_ssm
+
+import (
+	"kubevault.dev/unsealer/pkg/kv"
+	"kubevault.dev/unsealer/pkg/kv/aws_kms"
+	"kubevault.dev/unsealer/pkg/kv/backend"
+
+	"github.com/pkg/errors"
+)
+
+// Provider is the name of the options of the ssm storage
+const Provider = "ssm"
+
+func init() {
+	backend.RegisterOptions(Provider, func() backend.Options { return NewOptions() })
+	backend.RegisterStorage(&backend.Storage{
+		Name:        "ssm",
+		Description: "AWS SSM parameter store, it needs --ssm.use-secure-string or an encryption",
+		Validate: func(opts backend.OptionSet) []error {
+			return opts[Provider].(*Options).Validate()
+		},
+		New: func(opts backend.OptionSet) (kv.Service, error) {
+			o := opts[Provider].(*Options)
+			return New(o.UseSecureString, o.KeyPrefix)
+		},
+		RequireEncryption: func(opts backend.OptionSet) error {
+			if opts[Provider].(*Options).UseSecureString {
+				return nil
+			}
+			return errors.New("mode 'ssm' stores the values as plaintext parameters, use --ssm.use-secure-string or an encryption like 'ssm+awskms'")
+		},
+		// ssm parameters hold 4 KiB, a snapshot would take tens of thousands
+		// of them, more than the quota of standard parameters
+		NoSnapshots: true,
+	})
+	// the mode of earlier releases is either storage alone, or storage with
+	// aws kms
+	backend.RegisterAlias(&backend.Alias{
+		Name:        "aws-kms-ssm",
+		Description: "AWS SSM parameter store using AWS KMS, or secure string parameters with --ssm.use-secure-string",
+		Validate: func(opts backend.OptionSet) []error {
+			return validateAlias(opts[Provider].(*Options), opts[aws_kms.Provider].(*aws_kms.Options))
+		},
+		Resolve: func(opts backend.OptionSet) string {
+			if opts[Provider].(*Options).UseSecureString {
+				return "ssm"
+			}
+			return "ssm+awskms"
+		},
+	})
+}
+
+// validateAlias validates the options of the aws-kms-ssm mode, it needs
+// either a kms key or secure string parameters
+func validateAlias(o *Options, kms *aws_kms.Options) []error {
+	var errs []error
+	if kms.KmsKeyID == "" && !o.UseSecureString {
+		errs = append(errs, errors.New("--aws.kms-key-id or --ssm.use-secure-string must be defined"))
+	}
+	if kms.KmsKeyID != "" && o.UseSecureString {
+		errs = append(errs, errors.New("--aws.kms-key-id and --ssm.use-secure-string both are defined, but only one of them is needed"))
+	}
+	return errs
+}
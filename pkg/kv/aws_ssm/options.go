@@ -0,0 +1,54 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws_ssm
+
+import (
+	"github.com/spf13/pflag"
+)
+
+type Options struct {
+	// Use secure string parameter
+	// More info: https://docs.aws.amazon.com/systems-manager/latest/userguide/sysman-paramstore-about.html#sysman-paramstore-securestring
+	UseSecureString bool
+
+	// TODO: should make it auto generated
+	KeyPrefix string
+}
+
+func NewOptions() *Options {
+	return &Options{}
+}
+
+func (o *Options) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.KeyPrefix, "ssm.key-prefix", o.KeyPrefix, "The Key Prefix for SSM Parameter store")
+	fs.BoolVar(&o.UseSecureString, "ssm.use-secure-string", o.UseSecureString, "Use secure string parameter, for more info https://docs.aws.amazon.com/systems-manager/latest/userguide/sysman-paramstore-about.html#sysman-paramstore-securestring")
+
+	// the flags of earlier releases, when the ssm storage shared the options
+	// of aws kms
+	fs.StringVar(&o.KeyPrefix, "aws.ssm-key-prefix", o.KeyPrefix, "Alias of --ssm.key-prefix")
+	fs.BoolVar(&o.UseSecureString, "aws.use-secure-string", o.UseSecureString, "Alias of --ssm.use-secure-string")
+	_ = fs.MarkDeprecated("aws.ssm-key-prefix", "use --ssm.key-prefix instead")
+	_ = fs.MarkDeprecated("aws.use-secure-string", "use --ssm.use-secure-string instead")
+}
+
+func (o *Options) Validate() []error {
+	return nil
+}
+
+func (o *Options) Apply() error {
+	return nil
+}
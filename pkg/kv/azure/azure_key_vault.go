@@ -20,27 +20,28 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"net/http"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"kubevault.dev/unsealer/pkg/kv"
 
 	azurekv "github.com/Azure/azure-sdk-for-go/services/keyvault/2016-10-01/keyvault"
+	"github.com/Azure/go-autorest/autorest"
 	"github.com/Azure/go-autorest/autorest/to"
 	"github.com/pkg/errors"
 )
 
 type KVService struct {
 	KeyClient    azurekv.BaseClient
-	Ctx          context.Context
 	VaultBaseUrl string
 	SecretPrefix string
 }
 
 func NewKVService(opts *Options) (kv.Service, error) {
 	k := &KVService{
-		Ctx:          context.Background(),
 		VaultBaseUrl: opts.VaultBaseUrl,
 		SecretPrefix: opts.SecretPrefix,
 	}
@@ -59,13 +60,13 @@ func NewKVService(opts *Options) (kv.Service, error) {
 	return k, nil
 }
 
-func (k *KVService) Set(key string, value []byte) error {
+func (k *KVService) Set(ctx context.Context, key string, value []byte) error {
 	data := base64.StdEncoding.EncodeToString(value)
-	return k.SetSecret(strings.ReplaceAll(k.getKeyName(key), ".", "-"), data)
+	return k.SetSecret(ctx, k.secretName(key), data)
 }
 
-func (k *KVService) Get(key string) ([]byte, error) {
-	data, err := k.GetSecret(strings.ReplaceAll(k.getKeyName(key), ".", "-"))
+func (k *KVService) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := k.GetSecret(ctx, k.secretName(key))
 	if err != nil {
 		return nil, kv.NewNotFoundError("unable to get secret(%s) from key vault. reason: %v", key, err)
 	}
@@ -78,21 +79,91 @@ func (k *KVService) Get(key string) ([]byte, error) {
 	return value, nil
 }
 
-func (k *KVService) CheckWriteAccess() error {
+// Create sets the key if it has no secret yet. Key vault has no conditional
+// writes, so the secret is set and then its versions are compared: the first
+// version wins, a later version or one created in the same second is
+// disabled and the key is reported as existing.
+func (k *KVService) Create(ctx context.Context, key string, value []byte) error {
+	_, err := k.Versions(ctx, key)
+	if err == nil {
+		return kv.NewExistsError("secret(%s) exists in key vault", key)
+	} else if _, ok := err.(*kv.NotFoundError); !ok {
+		return err
+	}
+
+	parameter := azurekv.SecretSetParameters{
+		Value:       to.StringPtr(base64.StdEncoding.EncodeToString(value)),
+		ContentType: to.StringPtr("password"),
+	}
+	sb, err := k.KeyClient.SetSecret(ctx, k.VaultBaseUrl, k.secretName(key), parameter)
+	if err != nil {
+		return errors.Wrap(err, "unable to set secrets in key vault")
+	}
+	created := kv.Version{ID: filepath.Base(to.String(sb.ID))}
+	if sb.Attributes != nil && sb.Attributes.Created != nil {
+		created.Time = time.Time(*sb.Attributes.Created)
+	}
+
+	versions, err := k.Versions(ctx, key)
+	if err != nil {
+		return err
+	}
+	rival, ok := createdFirst(versions, created)
+	if ok {
+		return nil
+	}
+
+	_, err = k.KeyClient.UpdateSecret(ctx, k.VaultBaseUrl, k.secretName(key), created.ID, azurekv.SecretUpdateParameters{
+		SecretAttributes: &azurekv.SecretAttributes{Enabled: to.BoolPtr(false)},
+	})
+	if err != nil {
+		return errors.Wrapf(err, "unable to disable version(%s) of secret(%s) that conflicts with version(%s)", created.ID, key, rival.ID)
+	}
+	return kv.NewExistsError("secret(%s) was created by another writer", key)
+}
+
+// createdFirst reports whether the created version is the first of the
+// enabled versions, otherwise it returns the version it conflicts with.
+// Created times only have a resolution of a second, so the order of versions
+// created in the same second is not known. Such versions are ordered by their
+// id instead, so that exactly one of the writers wins.
+func createdFirst(versions []kv.Version, created kv.Version) (kv.Version, bool) {
+	for _, v := range versions {
+		if v.ID == created.ID {
+			created.Time = v.Time
+		}
+	}
+	for _, v := range versions {
+		if v.ID != created.ID && createdBefore(v, created) {
+			return v, false
+		}
+	}
+	return kv.Version{}, true
+}
+
+// createdBefore orders versions by their created time, then by their id.
+func createdBefore(a, b kv.Version) bool {
+	if a.Time.Equal(b.Time) {
+		return a.ID < b.ID
+	}
+	return a.Time.Before(b.Time)
+}
+
+func (k *KVService) CheckWriteAccess(ctx context.Context) error {
 	key := "vault-unsealer-dummy-file"
 	val := "read write access check"
 
-	err := k.Set(key, []byte(val))
+	err := k.Set(ctx, key, []byte(val))
 	if err != nil {
 		return errors.Wrap(err, "failed to write test file")
 	}
 
-	_, err = k.Get(key)
+	_, err = k.Get(ctx, key)
 	if err != nil {
 		return errors.Wrap(err, "failed to get test file")
 	}
 
-	_, err = k.KeyClient.DeleteSecret(k.Ctx, k.VaultBaseUrl, key)
+	err = k.Delete(ctx, key)
 	if err != nil {
 		return errors.Wrap(err, "failed to delete test file")
 	}
@@ -100,18 +171,112 @@ func (k *KVService) CheckWriteAccess() error {
 	return nil
 }
 
-func (k *KVService) Test(key string) error {
+// Delete removes the secret of the key. If soft delete is enabled for the key
+// vault, the secret is kept as a deleted secret until it is purged.
+func (k *KVService) Delete(ctx context.Context, key string) error {
+	_, err := k.KeyClient.DeleteSecret(ctx, k.VaultBaseUrl, k.secretName(key))
+	if err != nil && !isNotFound(err) {
+		return errors.Wrapf(err, "unable to delete secret(%s) from key vault", key)
+	}
+	return nil
+}
+
+// List returns the keys of the secrets whose name starts with prefix. The
+// dots of a key are stored as dashes, so they are listed as dashes.
+func (k *KVService) List(ctx context.Context, prefix string) ([]string, error) {
+	namePrefix := k.secretName(prefix)
+
+	resp, err := k.KeyClient.GetSecrets(ctx, k.VaultBaseUrl, to.Int32Ptr(25))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to list secrets")
+	}
+
+	var keys []string
+	for resp.NotDone() {
+		for _, item := range resp.Values() {
+			name := filepath.Base(to.String(item.ID))
+			if strings.HasPrefix(name, namePrefix) {
+				keys = append(keys, strings.TrimPrefix(name, k.SecretPrefix))
+			}
+		}
+
+		err = resp.NextWithContext(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to get next pages of secrets")
+		}
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (k *KVService) Test(ctx context.Context, key string) error {
 	return nil
 }
 
+// Versions returns the enabled versions of the secret of the key, the latest
+// created version is the current one
+func (k *KVService) Versions(ctx context.Context, key string) ([]kv.Version, error) {
+	resp, err := k.KeyClient.GetSecretVersions(ctx, k.VaultBaseUrl, k.secretName(key), to.Int32Ptr(25))
+	if isNotFound(err) {
+		return nil, kv.NewNotFoundError("secret(%s) not found in key vault", key)
+	} else if err != nil {
+		return nil, errors.Wrap(err, "unable to get secret versions")
+	}
+
+	var versions []kv.Version
+	for resp.NotDone() {
+		for _, item := range resp.Values() {
+			if !enabled(item) {
+				continue
+			}
+			v := kv.Version{ID: filepath.Base(to.String(item.ID))}
+			if item.Attributes != nil && item.Attributes.Created != nil {
+				v.Time = time.Time(*item.Attributes.Created)
+			}
+			versions = append(versions, v)
+		}
+
+		err = resp.NextWithContext(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to get next pages of version")
+		}
+	}
+	if len(versions) == 0 {
+		return nil, kv.NewNotFoundError("secret(%s) not found in key vault", key)
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return createdBefore(versions[j], versions[i])
+	})
+	versions[0].Current = true
+	return versions, nil
+}
+
+func (k *KVService) GetVersion(ctx context.Context, key, version string) ([]byte, error) {
+	sr, err := k.KeyClient.GetSecret(ctx, k.VaultBaseUrl, k.secretName(key), version)
+	if isNotFound(err) {
+		return nil, kv.NewNotFoundError("version(%s) of secret(%s) not found in key vault", version, key)
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "unable to get secret(%s) of version(%s)", key, version)
+	}
+
+	value, err := base64.StdEncoding.DecodeString(to.String(sr.Value))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode base64 string")
+	}
+
+	return value, nil
+}
+
 // SetSecret will store secret in azure key vault
-func (k *KVService) SetSecret(secretName, value string) error {
+func (k *KVService) SetSecret(ctx context.Context, secretName, value string) error {
 	parameter := azurekv.SecretSetParameters{
 		Value:       to.StringPtr(value),
 		ContentType: to.StringPtr("password"),
 	}
 
-	_, err := k.KeyClient.SetSecret(k.Ctx, k.VaultBaseUrl, secretName, parameter)
+	_, err := k.KeyClient.SetSecret(ctx, k.VaultBaseUrl, secretName, parameter)
 	if err != nil {
 		return errors.Wrap(err, "unable to set secrets in key vault")
 	}
@@ -120,12 +285,12 @@ func (k *KVService) SetSecret(secretName, value string) error {
 }
 
 // GetSecret will give secret in response
-func (k *KVService) GetSecret(secretName string) (*string, error) {
-	version, err := k.GetLatestVersionOfSecret(k.VaultBaseUrl, secretName)
+func (k *KVService) GetSecret(ctx context.Context, secretName string) (*string, error) {
+	version, err := k.GetLatestVersionOfSecret(ctx, k.VaultBaseUrl, secretName)
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to get latest version of secret")
 	}
-	sr, err := k.KeyClient.GetSecret(k.Ctx, k.VaultBaseUrl, secretName, version)
+	sr, err := k.KeyClient.GetSecret(ctx, k.VaultBaseUrl, secretName, version)
 	if err != nil {
 		return nil, errors.Wrapf(err, "unable to get secret(%s) of version(%s)", secretName, version)
 	}
@@ -133,11 +298,11 @@ func (k *KVService) GetSecret(secretName string) (*string, error) {
 	return sr.Value, nil
 }
 
-// GetLatestVersionOfSecret will give latest version of secret according to created time
-func (k *KVService) GetLatestVersionOfSecret(vaultBaseUrl, secretName string) (string, error) {
+// GetLatestVersionOfSecret will give latest enabled version of secret according to created time
+func (k *KVService) GetLatestVersionOfSecret(ctx context.Context, vaultBaseUrl, secretName string) (string, error) {
 	var version string
 	var createdTime time.Duration
-	resp, err := k.KeyClient.GetSecretVersions(k.Ctx, vaultBaseUrl, secretName, to.Int32Ptr(20))
+	resp, err := k.KeyClient.GetSecretVersions(ctx, vaultBaseUrl, secretName, to.Int32Ptr(20))
 	if err != nil {
 		return "", errors.Wrap(err, "unable to get secret versions")
 	}
@@ -146,6 +311,9 @@ func (k *KVService) GetLatestVersionOfSecret(vaultBaseUrl, secretName string) (s
 	for resp.NotDone() {
 		items := resp.Values()
 		for _, item := range items {
+			if !enabled(item) {
+				continue
+			}
 			if version == "" {
 				version = filepath.Base(to.String(item.ID))
 				createdTime = item.Attributes.Created.Duration()
@@ -155,7 +323,7 @@ func (k *KVService) GetLatestVersionOfSecret(vaultBaseUrl, secretName string) (s
 			}
 		}
 
-		err = resp.Next()
+		err = resp.NextWithContext(ctx)
 		if err != nil {
 			return "", errors.Wrap(err, "unable to get next pages of version")
 		}
@@ -166,3 +334,20 @@ func (k *KVService) GetLatestVersionOfSecret(vaultBaseUrl, secretName string) (s
 func (k *KVService) getKeyName(key string) string {
 	return fmt.Sprintf("%s%s", k.SecretPrefix, key)
 }
+
+// secretName returns the name of the secret that holds the key, secret names
+// can not contain dots
+func (k *KVService) secretName(key string) string {
+	return strings.ReplaceAll(k.getKeyName(key), ".", "-")
+}
+
+// enabled returns false for a version that is disabled, like a version that
+// lost a Create
+func enabled(item azurekv.SecretItem) bool {
+	return item.Attributes == nil || item.Attributes.Enabled == nil || *item.Attributes.Enabled
+}
+
+func isNotFound(err error) bool {
+	var de autorest.DetailedError
+	return errors.As(err, &de) && de.StatusCode == http.StatusNotFound
+}
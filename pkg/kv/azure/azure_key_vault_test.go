@@ -0,0 +1,94 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"testing"
+	"time"
+
+	"kubevault.dev/unsealer/pkg/kv"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreatedFirst(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+
+	testData := []struct {
+		testName string
+		versions []kv.Version
+		created  kv.Version
+		expected bool
+	}{
+		{
+			"only version",
+			[]kv.Version{{ID: "b", Time: now}},
+			kv.Version{ID: "b", Time: now},
+			true,
+		},
+		{
+			"later version is no conflict",
+			[]kv.Version{{ID: "a", Time: now.Add(time.Second)}, {ID: "b", Time: now}},
+			kv.Version{ID: "b", Time: now},
+			true,
+		},
+		{
+			"earlier version wins",
+			[]kv.Version{{ID: "b", Time: now.Add(time.Second)}, {ID: "c", Time: now}},
+			kv.Version{ID: "b", Time: now.Add(time.Second)},
+			false,
+		},
+		{
+			"version of the same second with a lower id wins",
+			[]kv.Version{{ID: "b", Time: now}, {ID: "c", Time: now}},
+			kv.Version{ID: "c", Time: now},
+			false,
+		},
+		{
+			"version of the same second with a higher id loses",
+			[]kv.Version{{ID: "b", Time: now}, {ID: "c", Time: now}},
+			kv.Version{ID: "b", Time: now},
+			true,
+		},
+		{
+			"created time of the listed version is used",
+			[]kv.Version{{ID: "b", Time: now}, {ID: "c", Time: now.Add(time.Second)}},
+			kv.Version{ID: "b"},
+			true,
+		},
+	}
+
+	for _, test := range testData {
+		t.Run(test.testName, func(t *testing.T) {
+			_, ok := createdFirst(test.versions, test.created)
+			assert.Equal(t, test.expected, ok)
+		})
+	}
+}
+
+func TestCreatedFirstEqualTimes(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	versions := []kv.Version{{ID: "d", Time: now}, {ID: "a", Time: now}, {ID: "c", Time: now}}
+
+	var winners []string
+	for _, v := range versions {
+		if _, ok := createdFirst(versions, v); ok {
+			winners = append(winners, v.ID)
+		}
+	}
+	assert.Equal(t, []string{"a"}, winners, "exactly one of the writers of the same second wins")
+}
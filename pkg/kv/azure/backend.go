@@ -0,0 +1,41 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package azure
+
+import (
+	"kubevault.dev/unsealer/pkg/kv"
+	"kubevault.dev/unsealer/pkg/kv/backend"
+)
+
+// Provider is the name of the options of the azure backends
+const Provider = "azure"
+
+func init() {
+	backend.RegisterOptions(Provider, func() backend.Options { return NewOptions() })
+	backend.RegisterStorage(&backend.Storage{
+		Name:        "azure-key-vault",
+		Description: "Azure Key Vault Secret store",
+		Validate: func(opts backend.OptionSet) []error {
+			return opts[Provider].(*Options).Validate()
+		},
+		New: func(opts backend.OptionSet) (kv.Service, error) {
+			return NewKVService(opts[Provider].(*Options))
+		},
+		// key vault secrets hold 25 KiB, the values are base64 encoded
+		SnapshotChunkSize: 16 << 10,
+	})
+}
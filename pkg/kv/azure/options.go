@@ -36,7 +36,7 @@ func NewOptions() *Options {
 
 func (o *Options) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&o.VaultBaseUrl, "azure.vault-base-url", o.VaultBaseUrl, "Azure key vault url, for example https://myvault.vault.azure.net")
-	fs.StringVar(&o.SecretPrefix, "azure.secret-prefix", o.VaultBaseUrl, "Prefix to use in secret name for azure key vault")
+	fs.StringVar(&o.SecretPrefix, "azure.secret-prefix", o.SecretPrefix, "Prefix to use in secret name for azure key vault")
 
 	o.AuthConfig.AddFlags(fs)
 }
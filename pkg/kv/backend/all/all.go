@@ -0,0 +1,28 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package all registers every key store backend, a new backend is added to
+// the unsealer by importing its package here.
+package all
+
+import (
+	_ "kubevault.dev/unsealer/pkg/kv/aws_kms"
+	_ "kubevault.dev/unsealer/pkg/kv/aws_ssm"
+	_ "kubevault.dev/unsealer/pkg/kv/azure"
+	_ "kubevault.dev/unsealer/pkg/kv/cloudkms"
+	_ "kubevault.dev/unsealer/pkg/kv/gcs"
+	_ "kubevault.dev/unsealer/pkg/kv/kubernetes"
+)
@@ -0,0 +1,255 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package backend is the registry of the key store backends. A mode is either
+// a storage backend, or a storage and an encryption backend joined by '+',
+// like 'gcs+awskms'. The values are encrypted by the encryption backend before
+// they are stored. The modes of earlier releases are kept as aliases.
+package backend
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"kubevault.dev/unsealer/pkg/kv"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+// Options are the options of the backends of a provider, they are shared by
+// every backend of the provider
+type Options interface {
+	AddFlags(fs *pflag.FlagSet)
+}
+
+// OptionSet holds the options of every registered provider
+type OptionSet map[string]Options
+
+// Storage is a backend that stores the values
+type Storage struct {
+	Name        string
+	Description string
+	// Validate validates the options the storage uses
+	Validate func(opts OptionSet) []error
+	New      func(opts OptionSet) (kv.Service, error)
+	// SnapshotChunkSize is the largest chunk of a raft snapshot the storage
+	// accepts after encoding, zero if it has no limit
+	SnapshotChunkSize int
+	// NoSnapshots is true if the storage is too small to hold raft snapshots
+	NoSnapshots bool
+	// RequireEncryption returns an error if the storage would keep the values
+	// in plaintext without an encryption, it is nil if the storage may be
+	// used alone
+	RequireEncryption func(opts OptionSet) error
+}
+
+// Encryption is a backend that encrypts the values of a storage, its
+// encrypter is applied over any storage
+type Encryption struct {
+	Name        string
+	Description string
+	// Validate validates the options the encryption uses
+	Validate     func(opts OptionSet) []error
+	NewEncrypter func(opts OptionSet) (kv.Encrypter, error)
+	// SnapshotChunkSize is the largest chunk of a raft snapshot the
+	// encryption accepts, zero if it has no limit
+	SnapshotChunkSize int
+}
+
+// Alias is a mode of an earlier release, it stands for a storage or a
+// storage+encryption mode that may depend on the options
+type Alias struct {
+	Name        string
+	Description string
+	// Validate validates the options the alias uses, the options of the
+	// mode it stands for are not validated
+	Validate func(opts OptionSet) []error
+	Resolve  func(opts OptionSet) string
+}
+
+var (
+	options     = map[string]func() Options{}
+	storages    = map[string]*Storage{}
+	encryptions = map[string]*Encryption{}
+	aliases     = map[string]*Alias{}
+)
+
+// RegisterOptions registers the options of a provider, newOptions returns
+// the options with their defaults. It panics if the provider is registered
+// already.
+func RegisterOptions(provider string, newOptions func() Options) {
+	if _, ok := options[provider]; ok {
+		panic(fmt.Sprintf("options of provider %q are registered twice", provider))
+	}
+	options[provider] = newOptions
+}
+
+// RegisterStorage registers a storage backend, it panics if the name is taken
+func RegisterStorage(s *Storage) {
+	mustBeFree(s.Name)
+	storages[s.Name] = s
+}
+
+// RegisterEncryption registers an encryption backend, it panics if the name is
+// taken
+func RegisterEncryption(e *Encryption) {
+	mustBeFree(e.Name)
+	encryptions[e.Name] = e
+}
+
+// RegisterAlias registers an alias, it panics if the name is taken
+func RegisterAlias(a *Alias) {
+	mustBeFree(a.Name)
+	aliases[a.Name] = a
+}
+
+func mustBeFree(name string) {
+	if strings.Contains(name, "+") {
+		panic(fmt.Sprintf("backend name %q contains '+'", name))
+	}
+	_, s := storages[name]
+	_, e := encryptions[name]
+	_, a := aliases[name]
+	if s || e || a {
+		panic(fmt.Sprintf("backend %q is registered twice", name))
+	}
+}
+
+// NewOptionSet returns the options of every registered provider
+func NewOptionSet() OptionSet {
+	set := OptionSet{}
+	for provider, newOptions := range options {
+		set[provider] = newOptions()
+	}
+	return set
+}
+
+// AddFlags adds the flags of every registered provider
+func (s OptionSet) AddFlags(fs *pflag.FlagSet) {
+	for _, provider := range sortedKeys(s) {
+		s[provider].AddFlags(fs)
+	}
+}
+
+// Mode is a storage backend and an optional encryption backend
+type Mode struct {
+	Storage    *Storage
+	Encryption *Encryption
+	// alias the mode was resolved from, if any
+	alias *Alias
+}
+
+// Parse returns the mode of the given name, an alias is resolved with opts
+func Parse(name string, opts OptionSet) (*Mode, error) {
+	var alias *Alias
+	if a, ok := aliases[name]; ok {
+		alias, name = a, a.Resolve(opts)
+	}
+
+	storage, encryption, _ := strings.Cut(name, "+")
+	m := &Mode{alias: alias}
+	var ok bool
+	if m.Storage, ok = storages[storage]; !ok {
+		return nil, errors.Errorf("invalid mode %q, unknown storage %q", name, storage)
+	}
+	if encryption != "" {
+		if m.Encryption, ok = encryptions[encryption]; !ok {
+			return nil, errors.Errorf("invalid mode %q, unknown encryption %q", name, encryption)
+		}
+	}
+	return m, nil
+}
+
+// Validate validates the options the mode uses
+func (m *Mode) Validate(opts OptionSet) []error {
+	if m.alias != nil {
+		return m.alias.Validate(opts)
+	}
+	errs := m.Storage.Validate(opts)
+	if m.Encryption != nil {
+		errs = append(errs, m.Encryption.Validate(opts)...)
+	} else if m.Storage.RequireEncryption != nil {
+		if err := m.Storage.RequireEncryption(opts); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// New returns the key store of the mode
+func (m *Mode) New(opts OptionSet) (kv.Service, error) {
+	store, err := m.Storage.New(opts)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create %s storage", m.Storage.Name)
+	}
+	if m.Encryption == nil {
+		return store, nil
+	}
+	encrypter, err := m.Encryption.NewEncrypter(opts)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create %s encryption", m.Encryption.Name)
+	}
+	return kv.WithEncryption(store, encrypter), nil
+}
+
+// SnapshotChunkSize returns the largest chunk of a raft snapshot the mode
+// accepts, zero if it has no limit
+func (m *Mode) SnapshotChunkSize() int {
+	size := m.Storage.SnapshotChunkSize
+	if m.Encryption != nil && m.Encryption.SnapshotChunkSize > 0 && (size == 0 || m.Encryption.SnapshotChunkSize < size) {
+		size = m.Encryption.SnapshotChunkSize
+	}
+	return size
+}
+
+func (m *Mode) String() string {
+	if m.Encryption == nil {
+		return m.Storage.Name
+	}
+	return m.Storage.Name + "+" + m.Encryption.Name
+}
+
+// Help describes the registered backends, for the help of a mode flag
+func Help() string {
+	var parts []string
+	for _, name := range sortedKeys(storages) {
+		parts = append(parts, fmt.Sprintf("'%s' => %s", name, storages[name].Description))
+	}
+	help := "Storages: " + strings.Join(parts, "; ")
+
+	parts = nil
+	for _, name := range sortedKeys(encryptions) {
+		parts = append(parts, fmt.Sprintf("'%s' => %s", name, encryptions[name].Description))
+	}
+	help += ". Encryptions: " + strings.Join(parts, "; ")
+
+	parts = nil
+	for _, name := range sortedKeys(aliases) {
+		parts = append(parts, fmt.Sprintf("'%s' => %s", name, aliases[name].Description))
+	}
+	return help + ". Aliases: " + strings.Join(parts, "; ")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
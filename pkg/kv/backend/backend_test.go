@@ -0,0 +1,103 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backend_test
+
+import (
+	"testing"
+
+	"kubevault.dev/unsealer/pkg/kv/aws_kms"
+	"kubevault.dev/unsealer/pkg/kv/aws_ssm"
+	"kubevault.dev/unsealer/pkg/kv/backend"
+	_ "kubevault.dev/unsealer/pkg/kv/backend/all"
+	"kubevault.dev/unsealer/pkg/kv/gcs"
+	"kubevault.dev/unsealer/pkg/kv/kubernetes"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	testData := []struct {
+		testName        string
+		mode            string
+		useSecureString bool
+		expected        string
+		chunkSize       int
+		expectErr       bool
+	}{
+		{"storage", "gcs", false, "gcs", 0, false},
+		{"storage and encryption", "gcs+awskms", false, "gcs+awskms", 2 << 10, false},
+		{"smallest chunk of both", "azure-key-vault+gcpkms", false, "azure-key-vault+gcpkms", 16 << 10, false},
+		{"alias", "google-cloud-kms-gcs", false, "gcs+gcpkms", 48 << 10, false},
+		{"alias resolved with the options", "aws-kms-ssm", true, "ssm", 0, false},
+		{"alias with kms", "aws-kms-ssm", false, "ssm+awskms", 2 << 10, false},
+		{"unknown storage", "s3", false, "", 0, true},
+		{"encryption is not a storage", "awskms", false, "", 0, true},
+		{"unknown encryption", "gcs+vault", false, "", 0, true},
+	}
+
+	for _, test := range testData {
+		t.Run(test.testName, func(t *testing.T) {
+			opts := backend.NewOptionSet()
+			opts[aws_ssm.Provider].(*aws_ssm.Options).UseSecureString = test.useSecureString
+
+			m, err := backend.Parse(test.mode, opts)
+			if test.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, m.String())
+			assert.Equal(t, test.chunkSize, m.SnapshotChunkSize())
+		})
+	}
+}
+
+func TestModeValidate(t *testing.T) {
+	opts := backend.NewOptionSet()
+
+	m, err := backend.Parse("kubernetes-secret+awskms", opts)
+	require.NoError(t, err)
+	assert.Len(t, m.Validate(opts), 2, "the options of both backends are validated")
+
+	// the alias keeps the validation of the mode it replaced
+	m, err = backend.Parse("aws-kms-ssm", opts)
+	require.NoError(t, err)
+	assert.Len(t, m.Validate(opts), 1)
+	opts[aws_kms.Provider].(*aws_kms.Options).KmsKeyID = "alias/vault"
+	assert.Empty(t, m.Validate(opts))
+}
+
+func TestModeValidatePlaintext(t *testing.T) {
+	opts := backend.NewOptionSet()
+	opts[gcs.Provider].(*gcs.Options).Bucket = "vault"
+
+	m, err := backend.Parse("gcs", opts)
+	require.NoError(t, err)
+	assert.Len(t, m.Validate(opts), 1, "gcs keeps the values in plaintext without an encryption")
+
+	m, err = backend.Parse("ssm", opts)
+	require.NoError(t, err)
+	assert.Len(t, m.Validate(opts), 1, "ssm keeps the values in plaintext without secure strings")
+	opts[aws_ssm.Provider].(*aws_ssm.Options).UseSecureString = true
+	assert.Empty(t, m.Validate(opts))
+
+	m, err = backend.Parse("kubernetes-secret", opts)
+	require.NoError(t, err)
+	opts[kubernetes.Provider].(*kubernetes.Options).SecretName = "vault-keys"
+	assert.Empty(t, m.Validate(opts), "a kubernetes secret may be used alone")
+}
@@ -27,18 +27,16 @@ import (
 	"google.golang.org/api/option"
 )
 
-// googleKms is an implementation of the kv.Service interface, that encrypts
-// and decrypts data using Google Cloud KMS before storing into another kv
-// backend.
+// googleKms is an implementation of the kv.Encrypter interface, that encrypts
+// and decrypts data using Google Cloud KMS.
 type googleKms struct {
 	svc     *cloudkms.Service
-	store   kv.Service
 	keyPath string
 }
 
-var _ kv.Service = &googleKms{}
+var _ kv.Encrypter = &googleKms{}
 
-func New(store kv.Service, project, location, keyring, cryptoKey string) (kv.Service, error) {
+func NewEncrypter(project, location, keyring, cryptoKey string) (kv.Encrypter, error) {
 	ctx := context.Background()
 	kmsService, err := cloudkms.NewService(ctx, option.WithScopes(cloudkms.CloudPlatformScope))
 	if err != nil {
@@ -46,16 +44,26 @@ func New(store kv.Service, project, location, keyring, cryptoKey string) (kv.Ser
 	}
 
 	return &googleKms{
-		store:   store,
 		svc:     kmsService,
 		keyPath: fmt.Sprintf("projects/%s/locations/%s/keyRings/%s/cryptoKeys/%s", project, location, keyring, cryptoKey),
 	}, nil
 }
 
-func (g *googleKms) encrypt(s []byte) ([]byte, error) {
+// New returns a store that encrypts the values of store with the Google Cloud
+// KMS crypto key
+func New(store kv.Service, project, location, keyring, cryptoKey string) (kv.Service, error) {
+	encrypter, err := NewEncrypter(project, location, keyring, cryptoKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return kv.WithEncryption(store, encrypter), nil
+}
+
+func (g *googleKms) Encrypt(ctx context.Context, s []byte) ([]byte, error) {
 	resp, err := g.svc.Projects.Locations.KeyRings.CryptoKeys.Encrypt(g.keyPath, &cloudkms.EncryptRequest{
 		Plaintext: base64.StdEncoding.EncodeToString(s),
-	}).Do()
+	}).Context(ctx).Do()
 	if err != nil {
 		return nil, fmt.Errorf("error encrypting data: %s", err.Error())
 	}
@@ -63,40 +71,13 @@ func (g *googleKms) encrypt(s []byte) ([]byte, error) {
 	return base64.StdEncoding.DecodeString(resp.Ciphertext)
 }
 
-func (g *googleKms) decrypt(s []byte) ([]byte, error) {
+func (g *googleKms) Decrypt(ctx context.Context, s []byte) ([]byte, error) {
 	resp, err := g.svc.Projects.Locations.KeyRings.CryptoKeys.Decrypt(g.keyPath, &cloudkms.DecryptRequest{
 		Ciphertext: base64.StdEncoding.EncodeToString(s),
-	}).Do()
+	}).Context(ctx).Do()
 	if err != nil {
 		return nil, fmt.Errorf("error decrypting data: %s", err.Error())
 	}
 
 	return base64.StdEncoding.DecodeString(resp.Plaintext)
 }
-
-func (g *googleKms) Get(key string) ([]byte, error) {
-	cipherText, err := g.store.Get(key)
-	if err != nil {
-		return nil, err
-	}
-
-	return g.decrypt(cipherText)
-}
-
-func (g *googleKms) Set(key string, val []byte) error {
-	cipherText, err := g.encrypt(val)
-	if err != nil {
-		return err
-	}
-
-	return g.store.Set(key, cipherText)
-}
-
-func (g *googleKms) CheckWriteAccess() error {
-	return g.store.CheckWriteAccess()
-}
-
-func (g *googleKms) Test(key string) error {
-	// TODO: Implement me properly
-	return nil
-}
@@ -28,10 +28,6 @@ type Options struct {
 	KmsKeyRing   string
 	KmsLocation  string
 	KmsProject   string
-
-	StorageBucket string // name of the Google Cloud Storage bucket to store values in
-	// TODO: should make it auto generated
-	StoragePrefix string // prefix to use for values store in Google Cloud Storage
 }
 
 func NewOptions() *Options {
@@ -43,8 +39,6 @@ func (o *Options) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&o.KmsKeyRing, "google.kms-key-ring", o.KmsKeyRing, "The name of the Google Cloud KMS key ring to use")
 	fs.StringVar(&o.KmsLocation, "google.kms-location", o.KmsLocation, "The Google Cloud KMS location to use (eg. 'global', 'europe-west1')")
 	fs.StringVar(&o.KmsProject, "google.kms-project", o.KmsProject, "The Google Cloud KMS project to use")
-	fs.StringVar(&o.StorageBucket, "google.storage-bucket", o.StorageBucket, "The name of the Google Cloud Storage bucket to store values in")
-	fs.StringVar(&o.StoragePrefix, "google.storage-prefix", o.StoragePrefix, "The prefix to use for values store in Google Cloud Storage")
 }
 
 func (o *Options) Validate() []error {
@@ -61,9 +55,6 @@ func (o *Options) Validate() []error {
 	if o.KmsProject == "" {
 		errs = append(errs, errors.New("google kms project must be non-empty"))
 	}
-	if o.StorageBucket == "" {
-		errs = append(errs, errors.New("google storage bucket name must be non-empty"))
-	}
 	return errs
 }
 
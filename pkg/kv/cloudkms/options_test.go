@@ -34,18 +34,15 @@ func getOptions() *Options {
 		nonEmpty,
 		nonEmpty,
 		nonEmpty,
-		nonEmpty,
-		nonEmpty,
 	}
 }
 
 func getValidationError() []error {
-	errs := make([]error, 0, 5)
+	errs := make([]error, 0, 4)
 	errs = append(errs, errors.New("google kms crypto key must be non-empty"))
 	errs = append(errs, errors.New("google kms key ring must be non-empty"))
 	errs = append(errs, errors.New("google kms location must be non-empty"))
 	errs = append(errs, errors.New("google kms project must be non-empty"))
-	errs = append(errs, errors.New("google storage bucket name must be non-empty"))
 	return errs
 }
 
@@ -91,15 +88,6 @@ func TestOptions_Validate(t *testing.T) {
 			}(),
 			errors.New("google kms project must be non-empty"),
 		},
-		{
-			"bucket is empty, validation failed",
-			func() *Options {
-				opts := getOptions()
-				opts.StorageBucket = ""
-				return opts
-			}(),
-			errors.New("google storage bucket name must be non-empty"),
-		},
 		{
 			"all is non empty, validation successful",
 			getOptions(),
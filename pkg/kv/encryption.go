@@ -0,0 +1,123 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kv
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+)
+
+// Encrypter encrypts values with a key it holds, like a key management
+// service. It is applied over a store by WithEncryption.
+type Encrypter interface {
+	Encrypt(ctx context.Context, plainText []byte) ([]byte, error)
+	Decrypt(ctx context.Context, cipherText []byte) ([]byte, error)
+}
+
+// encryptedService is an implementation of the Service interface that
+// encrypts the values before they are stored in the underlying store, and
+// decrypts them when they are read. The keys are not encrypted.
+type encryptedService struct {
+	store     Service
+	encrypter Encrypter
+}
+
+var _ Service = &encryptedService{}
+
+// WithEncryption returns a Service that stores the values in store encrypted
+// by encrypter
+func WithEncryption(store Service, encrypter Encrypter) Service {
+	return &encryptedService{
+		store:     store,
+		encrypter: encrypter,
+	}
+}
+
+func (e *encryptedService) Set(ctx context.Context, key string, value []byte) error {
+	cipherText, err := e.encrypter.Encrypt(ctx, value)
+	if err != nil {
+		return err
+	}
+	return e.store.Set(ctx, key, cipherText)
+}
+
+func (e *encryptedService) Get(ctx context.Context, key string) ([]byte, error) {
+	cipherText, err := e.store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return e.encrypter.Decrypt(ctx, cipherText)
+}
+
+func (e *encryptedService) Create(ctx context.Context, key string, value []byte) error {
+	cipherText, err := e.encrypter.Encrypt(ctx, value)
+	if err != nil {
+		return err
+	}
+	return e.store.Create(ctx, key, cipherText)
+}
+
+func (e *encryptedService) Delete(ctx context.Context, key string) error {
+	return e.store.Delete(ctx, key)
+}
+
+func (e *encryptedService) List(ctx context.Context, prefix string) ([]string, error) {
+	return e.store.List(ctx, prefix)
+}
+
+func (e *encryptedService) Versions(ctx context.Context, key string) ([]Version, error) {
+	return e.store.Versions(ctx, key)
+}
+
+func (e *encryptedService) GetVersion(ctx context.Context, key, version string) ([]byte, error) {
+	cipherText, err := e.store.GetVersion(ctx, key, version)
+	if err != nil {
+		return nil, err
+	}
+	return e.encrypter.Decrypt(ctx, cipherText)
+}
+
+func (e *encryptedService) CheckWriteAccess(ctx context.Context) error {
+	return e.store.CheckWriteAccess(ctx)
+}
+
+// Test tests the underlying store, and that a value survives encryption
+func (e *encryptedService) Test(ctx context.Context, key string) error {
+	inputString := "test"
+
+	err := e.store.Test(ctx, key)
+	if err != nil {
+		return fmt.Errorf("test of backend store failed: %s", err.Error())
+	}
+
+	cipherText, err := e.encrypter.Encrypt(ctx, []byte(inputString))
+	if err != nil {
+		return err
+	}
+
+	plainText, err := e.encrypter.Decrypt(ctx, cipherText)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(plainText, []byte(inputString)) {
+		return fmt.Errorf("encryped and decryped text doesn't match: exp: '%v', act: '%v'", inputString, string(plainText))
+	}
+
+	return nil
+}
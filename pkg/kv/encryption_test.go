@@ -0,0 +1,96 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kv_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"kubevault.dev/unsealer/pkg/internal/kvtest"
+	"kubevault.dev/unsealer/pkg/kv"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// reverser encrypts a value by reversing it
+type reverser struct {
+	broken bool
+}
+
+func (r reverser) Encrypt(ctx context.Context, plainText []byte) ([]byte, error) {
+	out := slices.Clone(plainText)
+	slices.Reverse(out)
+	return out, nil
+}
+
+func (r reverser) Decrypt(ctx context.Context, cipherText []byte) ([]byte, error) {
+	if r.broken {
+		return cipherText, nil
+	}
+	return r.Encrypt(ctx, cipherText)
+}
+
+func TestWithEncryption(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("values are stored encrypted", func(t *testing.T) {
+		store := kvtest.New(nil)
+		s := kv.WithEncryption(store, reverser{})
+
+		require.NoError(t, s.Set(ctx, "vault-root", []byte("token")))
+		assert.Equal(t, []byte("nekot"), store.Values()["vault-root"])
+
+		v, err := s.Get(ctx, "vault-root")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("token"), v)
+
+		v, err = s.GetVersion(ctx, "vault-root", "1")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("token"), v)
+	})
+
+	t.Run("created values are stored encrypted", func(t *testing.T) {
+		store := kvtest.New(nil)
+		s := kv.WithEncryption(store, reverser{})
+
+		require.NoError(t, s.Create(ctx, "vault-unseal-0", []byte("key")))
+		assert.Equal(t, []byte("yek"), store.Values()["vault-unseal-0"])
+
+		err := s.Create(ctx, "vault-unseal-0", []byte("other"))
+		assert.IsType(t, &kv.ExistsError{}, err)
+	})
+
+	t.Run("keys are not encrypted", func(t *testing.T) {
+		s := kv.WithEncryption(kvtest.New(nil), reverser{})
+
+		require.NoError(t, s.Set(ctx, "vault-unseal-0", []byte("key")))
+		keys, err := s.List(ctx, "vault-")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"vault-unseal-0"}, keys)
+
+		require.NoError(t, s.Delete(ctx, "vault-unseal-0"))
+		_, err = s.Get(ctx, "vault-unseal-0")
+		assert.IsType(t, &kv.NotFoundError{}, err)
+	})
+
+	t.Run("test fails if a value does not survive encryption", func(t *testing.T) {
+		assert.NoError(t, kv.WithEncryption(kvtest.New(nil), reverser{}).Test(ctx, "vault-test"))
+		assert.Error(t, kv.WithEncryption(kvtest.New(nil), reverser{broken: true}).Test(ctx, "vault-test"))
+	})
+}
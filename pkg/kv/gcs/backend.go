@@ -0,0 +1,57 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcs
+
+import (
+	"kubevault.dev/unsealer/pkg/kv"
+	"kubevault.dev/unsealer/pkg/kv/backend"
+	"kubevault.dev/unsealer/pkg/kv/cloudkms"
+
+	"github.com/pkg/errors"
+)
+
+// Provider is the name of the options of the gcs storage
+const Provider = "gcs"
+
+func init() {
+	backend.RegisterOptions(Provider, func() backend.Options { return NewOptions() })
+	backend.RegisterStorage(&backend.Storage{
+		Name:        "gcs",
+		Description: "Google Cloud Storage, it needs an encryption",
+		Validate: func(opts backend.OptionSet) []error {
+			return opts[Provider].(*Options).Validate()
+		},
+		New: func(opts backend.OptionSet) (kv.Service, error) {
+			o := opts[Provider].(*Options)
+			return New(o.Bucket, o.Prefix)
+		},
+		RequireEncryption: func(opts backend.OptionSet) error {
+			return errors.New("mode 'gcs' stores the values in plaintext, use an encryption like 'gcs+gcpkms'")
+		},
+	})
+	// the mode of earlier releases
+	backend.RegisterAlias(&backend.Alias{
+		Name:        "google-cloud-kms-gcs",
+		Description: "Google Cloud Storage with encryption using Google KMS",
+		Validate: func(opts backend.OptionSet) []error {
+			return append(opts[cloudkms.Provider].(*cloudkms.Options).Validate(), opts[Provider].(*Options).Validate()...)
+		},
+		Resolve: func(opts backend.OptionSet) string {
+			return "gcs+gcpkms"
+		},
+	})
+}
@@ -20,11 +20,18 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 
 	"kubevault.dev/unsealer/pkg/kv"
 
 	"cloud.google.com/go/storage"
 	"github.com/pkg/errors"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
+	"k8s.io/klog/v2"
 )
 
 type gcsStorage struct {
@@ -42,10 +49,29 @@ func New(bucket, prefix string) (kv.Service, error) {
 	return &gcsStorage{cl, bucket, prefix}, nil
 }
 
-func (g *gcsStorage) Set(key string, val []byte) error {
-	ctx := context.Background()
+func (g *gcsStorage) Set(ctx context.Context, key string, val []byte) error {
 	n := objectNameWithPrefix(g.prefix, key)
-	w := g.cl.Bucket(g.bucket).Object(n).NewWriter(ctx)
+	return g.write(ctx, g.cl.Bucket(g.bucket).Object(n), n, val)
+}
+
+// Create writes the object with a precondition that it does not exist, gcs
+// rejects the write if it does.
+func (g *gcsStorage) Create(ctx context.Context, key string, val []byte) error {
+	n := objectNameWithPrefix(g.prefix, key)
+	err := g.write(ctx, g.cl.Bucket(g.bucket).Object(n).If(storage.Conditions{DoesNotExist: true}), n, val)
+
+	var gerr *googleapi.Error
+	if errors.As(err, &gerr) && gerr.Code == http.StatusPreconditionFailed {
+		return kv.NewExistsError("object for key '%s' exists", n)
+	}
+	return err
+}
+
+func (g *gcsStorage) write(ctx context.Context, o *storage.ObjectHandle, n string, val []byte) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	w := o.NewWriter(ctx)
 	if _, err := w.Write(val); err != nil {
 		return fmt.Errorf("error writing key '%s' to gcs bucket '%s'", n, g.bucket)
 	}
@@ -53,8 +79,7 @@ func (g *gcsStorage) Set(key string, val []byte) error {
 	return w.Close()
 }
 
-func (g *gcsStorage) Get(key string) ([]byte, error) {
-	ctx := context.Background()
+func (g *gcsStorage) Get(ctx context.Context, key string) ([]byte, error) {
 	n := objectNameWithPrefix(g.prefix, key)
 
 	r, err := g.cl.Bucket(g.bucket).Object(n).NewReader(ctx)
@@ -64,6 +89,7 @@ func (g *gcsStorage) Get(key string) ([]byte, error) {
 		}
 		return nil, fmt.Errorf("error getting object for key '%s': %s", n, err.Error())
 	}
+	defer r.Close() //nolint:errcheck
 
 	b, err := io.ReadAll(r)
 	if err != nil {
@@ -73,34 +99,133 @@ func (g *gcsStorage) Get(key string) ([]byte, error) {
 	return b, nil
 }
 
-func (g *gcsStorage) CheckWriteAccess() error {
+func (g *gcsStorage) CheckWriteAccess(ctx context.Context) error {
 	key := "vault-unsealer-dummy-file"
 	val := "read write access check"
 
-	err := g.Set(key, []byte(val))
+	err := g.Set(ctx, key, []byte(val))
 	if err != nil {
 		return errors.Wrap(err, "failed to write test file")
 	}
 
-	_, err = g.Get(key)
+	_, err = g.Get(ctx, key)
 	if err != nil {
 		return errors.Wrap(err, "failed to get test file")
 	}
 
-	ctx := context.Background()
-
-	err = g.cl.Bucket(g.bucket).Object(key).Delete(ctx)
+	err = g.Delete(ctx, key)
 	if err != nil {
 		return errors.Wrap(err, "failed to delete test file")
 	}
+
+	// the previous values are only kept by a bucket with object versioning
+	attrs, err := g.cl.Bucket(g.bucket).Attrs(ctx)
+	if err == nil && !attrs.VersioningEnabled {
+		klog.Warningf("object versioning is not enabled for gcs bucket '%s', overwritten keys can not be restored", g.bucket)
+	}
 	return nil
 }
 
+func (g *gcsStorage) Delete(ctx context.Context, key string) error {
+	n := objectNameWithPrefix(g.prefix, key)
+
+	err := g.cl.Bucket(g.bucket).Object(n).Delete(ctx)
+	if err != nil && err != storage.ErrObjectNotExist {
+		return fmt.Errorf("error deleting object for key '%s': %s", n, err.Error())
+	}
+	return nil
+}
+
+func (g *gcsStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	it := g.cl.Bucket(g.bucket).Objects(ctx, &storage.Query{
+		Prefix: objectNameWithPrefix(g.prefix, prefix),
+	})
+
+	var keys []string
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error listing objects in gcs bucket '%s': %s", g.bucket, err.Error())
+		}
+		keys = append(keys, strings.TrimPrefix(attrs.Name, g.prefix))
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Versions returns the generations of the object of the key, the previous
+// generations are only kept if object versioning is enabled for the bucket.
+func (g *gcsStorage) Versions(ctx context.Context, key string) ([]kv.Version, error) {
+	n := objectNameWithPrefix(g.prefix, key)
+	it := g.cl.Bucket(g.bucket).Objects(ctx, &storage.Query{
+		Prefix:   n,
+		Versions: true,
+	})
+
+	var objects []*storage.ObjectAttrs
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error listing generations of object '%s': %s", n, err.Error())
+		}
+		if attrs.Name == n {
+			objects = append(objects, attrs)
+		}
+	}
+	if len(objects) == 0 {
+		return nil, kv.NewNotFoundError("no generation of object '%s' found", n)
+	}
+
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Generation > objects[j].Generation
+	})
+	versions := make([]kv.Version, 0, len(objects))
+	for _, attrs := range objects {
+		versions = append(versions, kv.Version{
+			ID:      strconv.FormatInt(attrs.Generation, 10),
+			Time:    attrs.Created,
+			Current: attrs.Deleted.IsZero(),
+		})
+	}
+	return versions, nil
+}
+
+func (g *gcsStorage) GetVersion(ctx context.Context, key, version string) ([]byte, error) {
+	n := objectNameWithPrefix(g.prefix, key)
+	gen, err := strconv.ParseInt(version, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid generation '%s' of object '%s'", version, n)
+	}
+
+	r, err := g.cl.Bucket(g.bucket).Object(n).Generation(gen).NewReader(ctx)
+	if err != nil {
+		if err == storage.ErrObjectNotExist {
+			return nil, kv.NewNotFoundError("error getting generation %d of object '%s': %s", gen, n, err.Error())
+		}
+		return nil, fmt.Errorf("error getting generation %d of object '%s': %s", gen, n, err.Error())
+	}
+	defer r.Close() //nolint:errcheck
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading generation %d of object '%s': %s", gen, n, err.Error())
+	}
+
+	return b, nil
+}
+
 func objectNameWithPrefix(prefix, key string) string {
 	return fmt.Sprintf("%s%s", prefix, key)
 }
 
-func (g *gcsStorage) Test(key string) error {
+func (g *gcsStorage) Test(ctx context.Context, key string) error {
 	// TODO: Implement me properly
 	return nil
 }
@@ -0,0 +1,56 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcs
+
+import (
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+type Options struct {
+	Bucket string // name of the Google Cloud Storage bucket to store values in
+	// TODO: should make it auto generated
+	Prefix string // prefix to use for values store in Google Cloud Storage
+}
+
+func NewOptions() *Options {
+	return &Options{}
+}
+
+func (o *Options) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.Bucket, "gcs.bucket", o.Bucket, "The name of the Google Cloud Storage bucket to store values in")
+	fs.StringVar(&o.Prefix, "gcs.prefix", o.Prefix, "The prefix to use for values store in Google Cloud Storage")
+
+	// the flags of earlier releases, when the gcs storage shared the options
+	// of google cloud kms
+	fs.StringVar(&o.Bucket, "google.storage-bucket", o.Bucket, "Alias of --gcs.bucket")
+	fs.StringVar(&o.Prefix, "google.storage-prefix", o.Prefix, "Alias of --gcs.prefix")
+	_ = fs.MarkDeprecated("google.storage-bucket", "use --gcs.bucket instead")
+	_ = fs.MarkDeprecated("google.storage-prefix", "use --gcs.prefix instead")
+}
+
+func (o *Options) Validate() []error {
+	var errs []error
+	if o.Bucket == "" {
+		errs = append(errs, errors.New("google storage bucket name must be non-empty"))
+	}
+	return errs
+}
+
+func (o *Options) Apply() error {
+	return nil
+}
@@ -0,0 +1,46 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptions_Validate(t *testing.T) {
+	testData := []struct {
+		testName    string
+		opts        *Options
+		expectedErr string
+	}{
+		{"bucket is provided, validation successful", &Options{Bucket: "vault"}, ""},
+		{"only the prefix is provided, validation failed", &Options{Prefix: "unsealer/"}, "google storage bucket name must be non-empty"},
+	}
+
+	for _, test := range testData {
+		t.Run(test.testName, func(t *testing.T) {
+			errs := test.opts.Validate()
+			if test.expectedErr != "" {
+				assert.Len(t, errs, 1)
+				assert.EqualError(t, errs[0], test.expectedErr)
+			} else {
+				assert.Nil(t, errs)
+			}
+		})
+	}
+}
@@ -23,6 +23,9 @@ import (
 
 type Options struct {
 	SecretName string
+	// HistoryLimit is the number of values kept for every key, the history
+	// is disabled if it is zero
+	HistoryLimit int
 }
 
 func NewOptions() *Options {
@@ -31,6 +34,7 @@ func NewOptions() *Options {
 
 func (o *Options) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&o.SecretName, "k8s.secret-name", o.SecretName, "Secret name to use when creating secret containing root token and shared keys")
+	fs.IntVar(&o.HistoryLimit, "k8s.history-limit", o.HistoryLimit, "Number of values kept for every key in the history secret <k8s.secret-name>-history, the history is disabled if it is 0. Old and deleted values of the unseal keys and the root token are copied unencrypted to this second secret. Enabling it needs RBAC to get, create and patch the secret <k8s.secret-name>-history as well")
 }
 
 func (o *Options) Validate() []error {
@@ -38,6 +42,9 @@ func (o *Options) Validate() []error {
 	if o.SecretName == "" {
 		errs = append(errs, errors.New("secret name must be non-empty"))
 	}
+	if o.HistoryLimit < 0 {
+		errs = append(errs, errors.New("history limit must not be negative"))
+	}
 	return errs
 }
 
@@ -17,8 +17,14 @@ limitations under the License.
 package kubernetes
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"kubevault.dev/unsealer/pkg/kv"
 
@@ -28,6 +34,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
 	core_util "kmodules.xyz/client-go/core/v1"
 	meta_util "kmodules.xyz/client-go/meta"
 	"kmodules.xyz/client-go/tools/clientcmd"
@@ -37,12 +44,16 @@ type KVService struct {
 	KubeClient kubernetes.Interface
 	SecretName string
 	Namespace  string
+	// HistoryLimit is the number of values kept for every key in the history
+	// secret, the history is disabled if it is zero
+	HistoryLimit int
 }
 
 func NewKVService(c *Options) (*KVService, error) {
 	k := &KVService{
-		SecretName: c.SecretName,
-		Namespace:  meta_util.PodNamespace(),
+		SecretName:   c.SecretName,
+		Namespace:    meta_util.PodNamespace(),
+		HistoryLimit: c.HistoryLimit,
 	}
 
 	config, err := rest.InClusterConfig()
@@ -59,16 +70,18 @@ func NewKVService(c *Options) (*KVService, error) {
 	return k, nil
 }
 
-func (k *KVService) Set(key string, value []byte) error {
+func (k *KVService) Set(ctx context.Context, key string, value []byte) error {
 	secretMeta := metav1.ObjectMeta{
 		Name:      k.SecretName,
 		Namespace: k.Namespace,
 	}
-	_, _, err := core_util.CreateOrPatchSecret(context.TODO(), k.KubeClient, secretMeta, func(s *corev1.Secret) *corev1.Secret {
+	var prev []byte
+	_, _, err := core_util.CreateOrPatchSecret(ctx, k.KubeClient, secretMeta, func(s *corev1.Secret) *corev1.Secret {
 		if s.Data == nil {
 			s.Data = map[string][]byte{}
 		}
 
+		prev = s.Data[key]
 		s.Data[key] = value
 		return s
 	}, metav1.PatchOptions{})
@@ -76,11 +89,75 @@ func (k *KVService) Set(key string, value []byte) error {
 		return errors.Wrapf(err, "failed set data in secret(%s)", k.SecretName)
 	}
 
+	k.recordHistory(ctx, key, prev, value)
 	return nil
 }
 
-func (k *KVService) Get(key string) ([]byte, error) {
-	sr, err := k.KubeClient.CoreV1().Secrets(k.Namespace).Get(context.TODO(), k.SecretName, metav1.GetOptions{})
+// Create adds the key to the secret if it does not hold it yet. The secret is
+// updated with the resource version it was read with, so a concurrent change
+// makes the update fail and the key is checked again.
+func (k *KVService) Create(ctx context.Context, key string, value []byte) error {
+	var err error
+	for i := 0; i < createAttempts; i++ {
+		err = k.create(ctx, key, value)
+		if !kerror.IsConflict(err) && !kerror.IsAlreadyExists(err) {
+			break
+		}
+	}
+	if err != nil {
+		if _, ok := err.(*kv.ExistsError); ok {
+			return err
+		}
+		return errors.Wrapf(err, "failed create data in secret(%s)", k.SecretName)
+	}
+
+	k.recordHistory(ctx, key, nil, value)
+	return nil
+}
+
+// recordHistory records the value in the history if it is enabled. The value
+// is stored already, so a failure is only logged: failing the write would
+// make the caller take a stored value for a lost one.
+func (k *KVService) recordHistory(ctx context.Context, key string, prev, value []byte) {
+	if k.HistoryLimit <= 0 {
+		return
+	}
+	if err := k.recordVersion(ctx, key, prev, value); err != nil {
+		klog.Warningf("failed to record the history of key(%s) in secret(%s) with %s", key, k.historySecretName(), err.Error())
+	}
+}
+
+const createAttempts = 5
+
+func (k *KVService) create(ctx context.Context, key string, value []byte) error {
+	secrets := k.KubeClient.CoreV1().Secrets(k.Namespace)
+	sr, err := secrets.Get(ctx, k.SecretName, metav1.GetOptions{})
+	if kerror.IsNotFound(err) {
+		_, err = secrets.Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      k.SecretName,
+				Namespace: k.Namespace,
+			},
+			Data: map[string][]byte{key: value},
+		}, metav1.CreateOptions{})
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	if _, ok := sr.Data[key]; ok {
+		return kv.NewExistsError("key(%s) exists in secret(%s)", key, k.SecretName)
+	}
+	if sr.Data == nil {
+		sr.Data = map[string][]byte{}
+	}
+	sr.Data[key] = value
+	_, err = secrets.Update(ctx, sr, metav1.UpdateOptions{})
+	return err
+}
+
+func (k *KVService) Get(ctx context.Context, key string) ([]byte, error) {
+	sr, err := k.KubeClient.CoreV1().Secrets(k.Namespace).Get(ctx, k.SecretName, metav1.GetOptions{})
 	if kerror.IsNotFound(err) {
 		return nil, kv.NewNotFoundError("secret not found. reason: %v", err)
 	} else if err != nil {
@@ -98,46 +175,235 @@ func (k *KVService) Get(key string) ([]byte, error) {
 	}
 }
 
-func (k *KVService) CheckWriteAccess() error {
+func (k *KVService) CheckWriteAccess(ctx context.Context) error {
 	key := "vault-unsealer-dummy-file"
 	val := "read write access check"
 
-	err := k.Set(key, []byte(val))
+	err := k.Set(ctx, key, []byte(val))
 	if err != nil {
 		return errors.Wrap(err, "failed to write test data")
 	}
 
-	_, err = k.Get(key)
+	_, err = k.Get(ctx, key)
 	if err != nil {
 		return errors.Wrap(err, "failed to get test data")
 	}
 
-	sr, err := k.KubeClient.CoreV1().Secrets(k.Namespace).Get(context.TODO(), k.SecretName, metav1.GetOptions{})
+	err = k.Delete(ctx, key)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete test data")
+	}
+
+	return nil
+}
+
+// Delete removes the key from the secret. Its history is kept, so that a key
+// deleted by mistake can be restored, and the deleted value is added to the
+// history first if it is enabled.
+func (k *KVService) Delete(ctx context.Context, key string) error {
+	sr, err := k.KubeClient.CoreV1().Secrets(k.Namespace).Get(ctx, k.SecretName, metav1.GetOptions{})
 	if kerror.IsNotFound(err) {
-		return kv.NewNotFoundError("secret not found. reason: %v", err)
+		return nil
 	} else if err != nil {
 		return fmt.Errorf("failed to get secret. reason: %v", err)
 	}
 
-	newData := map[string][]byte{}
-
-	for k, v := range sr.Data {
-		if k != key {
-			newData[k] = v
+	value, ok := sr.Data[key]
+	if !ok {
+		return nil
+	}
+	if k.HistoryLimit > 0 {
+		if err := k.recordVersion(ctx, key, nil, value); err != nil {
+			return errors.Wrapf(err, "failed to keep the value of key(%s) in the history before deleting it", key)
 		}
 	}
 
-	_, _, err = core_util.CreateOrPatchSecret(context.TODO(), k.KubeClient, sr.ObjectMeta, func(s *corev1.Secret) *corev1.Secret {
-		s.Data = newData
+	_, _, err = core_util.PatchSecret(ctx, k.KubeClient, sr, func(s *corev1.Secret) *corev1.Secret {
+		delete(s.Data, key)
 		return s
 	}, metav1.PatchOptions{})
 	if err != nil {
 		return errors.Wrapf(err, "failed delete data in secret(%s)", k.SecretName)
 	}
+	return nil
+}
 
+func (k *KVService) List(ctx context.Context, prefix string) ([]string, error) {
+	sr, err := k.KubeClient.CoreV1().Secrets(k.Namespace).Get(ctx, k.SecretName, metav1.GetOptions{})
+	if kerror.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get secret. reason: %v", err)
+	}
+
+	var keys []string
+	for key := range sr.Data {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (k *KVService) Test(ctx context.Context, key string) error {
 	return nil
 }
 
-func (k *KVService) Test(key string) error {
+// historyEntry is a value of a key kept in the history secret
+type historyEntry struct {
+	Time  time.Time `json:"time"`
+	Value []byte    `json:"value"`
+}
+
+// historySecretName returns the name of the secret that keeps the previous
+// values of the keys, the values of a key are stored as <key>.<version>.
+func (k *KVService) historySecretName() string {
+	return k.SecretName + "-history"
+}
+
+func (k *KVService) getHistorySecret(ctx context.Context) (*corev1.Secret, error) {
+	sr, err := k.KubeClient.CoreV1().Secrets(k.Namespace).Get(ctx, k.historySecretName(), metav1.GetOptions{})
+	if kerror.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get history secret. reason: %v", err)
+	}
+	return sr, nil
+}
+
+// versionsOf returns the versions of the key in the history, the newest first
+func versionsOf(data map[string][]byte, key string) []int {
+	var versions []int
+	for name := range data {
+		i := strings.LastIndex(name, ".")
+		if i < 0 || name[:i] != key {
+			continue
+		}
+		if v, err := strconv.Atoi(name[i+1:]); err == nil {
+			versions = append(versions, v)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+	return versions
+}
+
+func historyKey(key string, version int) string {
+	return fmt.Sprintf("%s.%d", key, version)
+}
+
+// recordVersion adds value to the history of the key
+func (k *KVService) recordVersion(ctx context.Context, key string, prev, value []byte) error {
+	secretMeta := metav1.ObjectMeta{
+		Name:      k.historySecretName(),
+		Namespace: k.Namespace,
+	}
+	_, _, err := core_util.CreateOrPatchSecret(ctx, k.KubeClient, secretMeta, func(s *corev1.Secret) *corev1.Secret {
+		if s.Data == nil {
+			s.Data = map[string][]byte{}
+		}
+
+		addVersion(s.Data, key, prev, value, time.Now().UTC(), k.HistoryLimit)
+		return s
+	}, metav1.PatchOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed set data in secret(%s)", k.historySecretName())
+	}
 	return nil
 }
+
+// addVersion adds value to the history of the key in data and drops the
+// versions beyond limit. A value that is equal to the newest version is not
+// added again. prev is the value that was replaced, it is kept as version 0
+// if the key had no history yet.
+func addVersion(data map[string][]byte, key string, prev, value []byte, now time.Time, limit int) {
+	versions := versionsOf(data, key)
+	next := 1
+	if len(versions) > 0 {
+		latest := historyEntry{}
+		if json.Unmarshal(data[historyKey(key, versions[0])], &latest) == nil && bytes.Equal(latest.Value, value) {
+			return
+		}
+		next = versions[0] + 1
+	} else if prev != nil && !bytes.Equal(prev, value) {
+		data[historyKey(key, 0)], _ = json.Marshal(historyEntry{Value: prev})
+		versions = []int{0}
+	}
+
+	data[historyKey(key, next)], _ = json.Marshal(historyEntry{Time: now, Value: value})
+	versions = append([]int{next}, versions...)
+	for _, v := range versions[min(len(versions), limit):] {
+		delete(data, historyKey(key, v))
+	}
+}
+
+// Versions returns the versions of the key kept in the history secret. A
+// value that was set while the history was disabled is listed as version 0.
+func (k *KVService) Versions(ctx context.Context, key string) ([]kv.Version, error) {
+	current, err := k.Get(ctx, key)
+	if _, ok := err.(*kv.NotFoundError); ok {
+		current = nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	sr, err := k.getHistorySecret(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []kv.Version
+	if sr != nil {
+		for _, v := range versionsOf(sr.Data, key) {
+			entry := historyEntry{}
+			if err := json.Unmarshal(sr.Data[historyKey(key, v)], &entry); err != nil {
+				return nil, errors.Wrapf(err, "failed to decode version %d of key(%s)", v, key)
+			}
+			versions = append(versions, kv.Version{
+				ID:   strconv.Itoa(v),
+				Time: entry.Time,
+				// only the newest version can be the current one
+				Current: len(versions) == 0 && current != nil && bytes.Equal(entry.Value, current),
+			})
+		}
+	}
+
+	if len(versions) == 0 {
+		if current == nil {
+			return nil, kv.NewNotFoundError("key(%s) not found in secret(%s)", key, k.SecretName)
+		}
+		versions = append(versions, kv.Version{ID: "0", Current: true})
+	}
+	return versions, nil
+}
+
+func (k *KVService) GetVersion(ctx context.Context, key, version string) ([]byte, error) {
+	v, err := strconv.Atoi(version)
+	if err != nil {
+		return nil, errors.Errorf("invalid version %q of key(%s)", version, key)
+	}
+
+	sr, err := k.getHistorySecret(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var data map[string][]byte
+	if sr != nil {
+		data = sr.Data
+	}
+	if raw, ok := data[historyKey(key, v)]; ok {
+		entry := historyEntry{}
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode version %d of key(%s)", v, key)
+		}
+		return entry.Value, nil
+	}
+
+	// the value that was set before the key had a history
+	if v == 0 && len(versionsOf(data, key)) == 0 {
+		return k.Get(ctx, key)
+	}
+	return nil, kv.NewNotFoundError("version %d of key(%s) not found in secret(%s)", v, key, k.historySecretName())
+}
@@ -0,0 +1,64 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddVersion(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	valueOf := func(t *testing.T, data map[string][]byte, name string) string {
+		entry := historyEntry{}
+		require.NoError(t, json.Unmarshal(data[name], &entry), name)
+		return string(entry.Value)
+	}
+
+	t.Run("keeps the value set before the history as version 0", func(t *testing.T) {
+		data := map[string][]byte{}
+		addVersion(data, "vault-root-token", []byte("old"), []byte("new"), now, 10)
+
+		assert.Equal(t, []int{1, 0}, versionsOf(data, "vault-root-token"))
+		assert.Equal(t, "old", valueOf(t, data, "vault-root-token.0"))
+		assert.Equal(t, "new", valueOf(t, data, "vault-root-token.1"))
+	})
+
+	t.Run("does not add the newest value again", func(t *testing.T) {
+		data := map[string][]byte{}
+		addVersion(data, "vault-root-token", nil, []byte("a"), now, 10)
+		addVersion(data, "vault-root-token", []byte("a"), []byte("a"), now, 10)
+
+		assert.Equal(t, []int{1}, versionsOf(data, "vault-root-token"))
+	})
+
+	t.Run("drops the versions beyond the limit", func(t *testing.T) {
+		data := map[string][]byte{}
+		for _, v := range []string{"a", "b", "c", "d"} {
+			addVersion(data, "vault-unseal-key-1", nil, []byte(v), now, 2)
+		}
+		addVersion(data, "vault-unseal-key-10", nil, []byte("x"), now, 2)
+
+		assert.Equal(t, []int{4, 3}, versionsOf(data, "vault-unseal-key-1"))
+		assert.Equal(t, "d", valueOf(t, data, "vault-unseal-key-1.4"))
+		assert.Equal(t, []int{1}, versionsOf(data, "vault-unseal-key-10"))
+	})
+}
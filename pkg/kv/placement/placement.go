@@ -0,0 +1,201 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"kubevault.dev/unsealer/pkg/kv"
+
+	"github.com/pkg/errors"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// shareKey matches the keys that hold an unseal or recovery share, including
+// their custodian and staged copies, the index of the share is captured.
+var shareKey = regexp.MustCompile(`(?:unseal|recovery)-key-(\d+)$`)
+
+// Rule places the shares with an index between From and To, both included,
+// in the store of the given mode.
+type Rule struct {
+	From int
+	To   int
+	Mode string
+}
+
+// ParseRule parses a rule of the form "<from>-<to>=<mode>" or "<index>=<mode>",
+// e.g. "0-1=aws-kms-ssm".
+func ParseRule(s string) (Rule, error) {
+	shares, mode, ok := strings.Cut(s, "=")
+	if !ok || mode == "" {
+		return Rule{}, errors.Errorf("invalid share placement %q, expected <from>-<to>=<mode>", s)
+	}
+
+	from, to, isRange := strings.Cut(shares, "-")
+	if !isRange {
+		to = from
+	}
+	var r Rule
+	var err error
+	if r.From, err = strconv.Atoi(from); err != nil {
+		return Rule{}, errors.Errorf("invalid share placement %q, %q is not a share index", s, from)
+	}
+	if r.To, err = strconv.Atoi(to); err != nil {
+		return Rule{}, errors.Errorf("invalid share placement %q, %q is not a share index", s, to)
+	}
+	if r.From < 0 || r.To < r.From {
+		return Rule{}, errors.Errorf("invalid share placement %q, invalid share range", s)
+	}
+	r.Mode = mode
+	return r, nil
+}
+
+// ParseRules parses every rule, the rules must not overlap.
+func ParseRules(rules []string) ([]Rule, error) {
+	parsed := make([]Rule, 0, len(rules))
+	for _, s := range rules {
+		r, err := ParseRule(s)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range parsed {
+			if r.From <= p.To && p.From <= r.To {
+				return nil, errors.Errorf("share placement %q overlaps with %d-%d=%s", s, p.From, p.To, p.Mode)
+			}
+		}
+		parsed = append(parsed, r)
+	}
+	return parsed, nil
+}
+
+type route struct {
+	Rule
+	store kv.Service
+}
+
+// placementService is an implementation of the Service interface that places
+// the unseal and recovery shares in several stores, so that no single store
+// holds enough shares to unseal vault. Every other key goes to the primary
+// store.
+type placementService struct {
+	primary kv.Service
+	routes  []route
+}
+
+var _ kv.Service = &placementService{}
+
+// New returns a Service that places the shares according to rules, stores
+// holds the store of every mode used by the rules. Shares that are not
+// covered by any rule go to primary.
+func New(primary kv.Service, rules []Rule, stores map[string]kv.Service) (kv.Service, error) {
+	p := &placementService{primary: primary}
+	for _, r := range rules {
+		store, ok := stores[r.Mode]
+		if !ok {
+			return nil, errors.Errorf("no store for mode %q", r.Mode)
+		}
+		p.routes = append(p.routes, route{Rule: r, store: store})
+	}
+	return p, nil
+}
+
+// storeFor returns the store that holds the key
+func (p *placementService) storeFor(key string) kv.Service {
+	m := shareKey.FindStringSubmatch(key)
+	if m == nil {
+		return p.primary
+	}
+	i, err := strconv.Atoi(m[1])
+	if err != nil {
+		return p.primary
+	}
+	for _, r := range p.routes {
+		if r.From <= i && i <= r.To {
+			return r.store
+		}
+	}
+	return p.primary
+}
+
+func (p *placementService) Set(ctx context.Context, key string, value []byte) error {
+	return p.storeFor(key).Set(ctx, key, value)
+}
+
+func (p *placementService) Get(ctx context.Context, key string) ([]byte, error) {
+	return p.storeFor(key).Get(ctx, key)
+}
+
+func (p *placementService) Create(ctx context.Context, key string, value []byte) error {
+	return p.storeFor(key).Create(ctx, key, value)
+}
+
+func (p *placementService) Delete(ctx context.Context, key string) error {
+	return p.storeFor(key).Delete(ctx, key)
+}
+
+// List returns the keys of every store, the store of a mode that is used by
+// several rules is listed once
+func (p *placementService) List(ctx context.Context, prefix string) ([]string, error) {
+	stores := []kv.Service{p.primary}
+	modes := sets.New[string]()
+	for _, r := range p.routes {
+		if !modes.Has(r.Mode) {
+			modes.Insert(r.Mode)
+			stores = append(stores, r.store)
+		}
+	}
+
+	keys := sets.New[string]()
+	for _, store := range stores {
+		list, err := store.List(ctx, prefix)
+		if err != nil {
+			return nil, err
+		}
+		keys.Insert(list...)
+	}
+	return sets.List(keys), nil
+}
+
+func (p *placementService) Versions(ctx context.Context, key string) ([]kv.Version, error) {
+	return p.storeFor(key).Versions(ctx, key)
+}
+
+func (p *placementService) GetVersion(ctx context.Context, key, version string) ([]byte, error) {
+	return p.storeFor(key).GetVersion(ctx, key, version)
+}
+
+// CheckWriteAccess checks the write access to every store
+func (p *placementService) CheckWriteAccess(ctx context.Context) error {
+	errs := []error{}
+	if err := p.primary.CheckWriteAccess(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	for _, r := range p.routes {
+		if err := r.store.CheckWriteAccess(ctx); err != nil {
+			errs = append(errs, errors.Wrapf(err, "store of shares %d-%d", r.From, r.To))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func (p *placementService) Test(ctx context.Context, key string) error {
+	return p.storeFor(key).Test(ctx, key)
+}
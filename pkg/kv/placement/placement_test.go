@@ -0,0 +1,106 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"context"
+	"testing"
+
+	"kubevault.dev/unsealer/pkg/internal/kvtest"
+	"kubevault.dev/unsealer/pkg/kv"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRules(t *testing.T) {
+	testData := []struct {
+		testName string
+		rules    []string
+		expected []Rule
+		wantErr  bool
+	}{
+		{
+			"range and single share",
+			[]string{"0-1=aws-kms-ssm", "4=kubernetes-secret"},
+			[]Rule{{0, 1, "aws-kms-ssm"}, {4, 4, "kubernetes-secret"}},
+			false,
+		},
+		{"missing mode", []string{"0-1="}, nil, true},
+		{"missing index", []string{"aws-kms-ssm"}, nil, true},
+		{"invalid index", []string{"a-1=aws-kms-ssm"}, nil, true},
+		{"reversed range", []string{"3-1=aws-kms-ssm"}, nil, true},
+		{"overlapping ranges", []string{"0-2=aws-kms-ssm", "2-3=kubernetes-secret"}, nil, true},
+	}
+
+	for _, test := range testData {
+		t.Run(test.testName, func(t *testing.T) {
+			rules, err := ParseRules(test.rules)
+			if test.wantErr {
+				assert.Error(t, err)
+			} else if assert.NoError(t, err) {
+				assert.Equal(t, test.expected, rules)
+			}
+		})
+	}
+}
+
+func TestPlacement(t *testing.T) {
+	primary, aws, k8s := kvtest.New(nil), kvtest.New(nil), kvtest.New(nil)
+	rules, err := ParseRules([]string{"0-1=aws-kms-ssm", "2=kubernetes-secret"})
+	require.NoError(t, err)
+	store, err := New(primary, rules, map[string]kv.Service{"aws-kms-ssm": aws, "kubernetes-secret": k8s})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	for _, key := range []string{
+		"vault-unseal-key-0", "vault-unseal-key-1", "vault-unseal-key-2", "vault-unseal-key-3",
+		"vault-recovery-key-1", "vault-custodian-unseal-key-2", "vault-staged-unseal-key-0",
+		"vault-root-token",
+	} {
+		require.NoError(t, store.Set(ctx, key, []byte(key)))
+	}
+
+	assert.Equal(t, map[string][]byte{
+		"vault-unseal-key-0":        []byte("vault-unseal-key-0"),
+		"vault-unseal-key-1":        []byte("vault-unseal-key-1"),
+		"vault-recovery-key-1":      []byte("vault-recovery-key-1"),
+		"vault-staged-unseal-key-0": []byte("vault-staged-unseal-key-0"),
+	}, aws.Values())
+	assert.Equal(t, map[string][]byte{
+		"vault-unseal-key-2":           []byte("vault-unseal-key-2"),
+		"vault-custodian-unseal-key-2": []byte("vault-custodian-unseal-key-2"),
+	}, k8s.Values())
+	assert.Equal(t, map[string][]byte{
+		"vault-unseal-key-3": []byte("vault-unseal-key-3"),
+		"vault-root-token":   []byte("vault-root-token"),
+	}, primary.Values())
+
+	v, err := store.Get(ctx, "vault-unseal-key-1")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("vault-unseal-key-1"), v)
+
+	keys, err := store.List(ctx, "vault-unseal-")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"vault-unseal-key-0", "vault-unseal-key-1", "vault-unseal-key-2", "vault-unseal-key-3"}, keys)
+
+	require.NoError(t, store.Delete(ctx, "vault-unseal-key-2"))
+	assert.NotContains(t, k8s.Values(), "vault-unseal-key-2")
+
+	_, err = New(primary, rules, map[string]kv.Service{"aws-kms-ssm": aws})
+	assert.Error(t, err, "every mode needs a store")
+}
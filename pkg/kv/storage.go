@@ -16,7 +16,11 @@ limitations under the License.
 
 package kv
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+	"time"
+)
 
 type NotFoundError struct {
 	msg string // description of error
@@ -30,11 +34,52 @@ func NewNotFoundError(msg string, args ...any) *NotFoundError {
 	}
 }
 
+// ExistsError is returned by Create if the key exists already
+type ExistsError struct {
+	msg string // description of error
+}
+
+func (e *ExistsError) Error() string { return e.msg }
+
+func NewExistsError(msg string, args ...any) *ExistsError {
+	return &ExistsError{
+		msg: fmt.Sprintf(msg, args...),
+	}
+}
+
+// Version describes a value that was set for a key
+type Version struct {
+	// ID identifies the version in the store
+	ID string
+	// Time is when the value was set, it is zero if the store does not know
+	Time time.Time
+	// Current is true for the version that Get returns
+	Current bool
+}
+
 // Service defines a basic key-value store. Implementations of this interface
 // may or may not guarantee consistency or security properties.
+//
+// Every call takes a context, implementations must give up as soon as the
+// context is cancelled or its deadline is exceeded.
 type Service interface {
-	Set(key string, value []byte) error
-	Get(key string) ([]byte, error)
-	CheckWriteAccess() error
-	Test(key string) error
+	Set(ctx context.Context, key string, value []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Create sets the key only if it does not exist, it returns an
+	// ExistsError otherwise. Of several concurrent calls for the same key
+	// exactly one succeeds.
+	Create(ctx context.Context, key string, value []byte) error
+	// Delete removes the key, deleting a key that does not exist is not an
+	// error.
+	Delete(ctx context.Context, key string) error
+	// List returns the keys that start with prefix, in lexical order.
+	List(ctx context.Context, prefix string) ([]string, error)
+	// Versions returns the versions of the key, the newest first. A store
+	// keeps a version for every value set, up to a limit of the store.
+	Versions(ctx context.Context, key string) ([]Version, error)
+	// GetVersion returns the value of a version of the key, as returned by
+	// Versions.
+	GetVersion(ctx context.Context, key, version string) ([]byte, error)
+	CheckWriteAccess(ctx context.Context) error
+	Test(ctx context.Context, key string) error
 }
@@ -0,0 +1,97 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kv
+
+import (
+	"context"
+	"time"
+)
+
+// timeoutService is an implementation of the Service interface that bounds
+// every call to the underlying store with its own deadline.
+type timeoutService struct {
+	store   Service
+	timeout time.Duration
+}
+
+var _ Service = &timeoutService{}
+
+// WithTimeout returns a Service that applies timeout to every call made to
+// store. If timeout is not positive, store is returned as it is.
+func WithTimeout(store Service, timeout time.Duration) Service {
+	if timeout <= 0 {
+		return store
+	}
+	return &timeoutService{
+		store:   store,
+		timeout: timeout,
+	}
+}
+
+func (t *timeoutService) Set(ctx context.Context, key string, value []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	return t.store.Set(ctx, key, value)
+}
+
+func (t *timeoutService) Get(ctx context.Context, key string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	return t.store.Get(ctx, key)
+}
+
+func (t *timeoutService) Create(ctx context.Context, key string, value []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	return t.store.Create(ctx, key, value)
+}
+
+func (t *timeoutService) Delete(ctx context.Context, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	return t.store.Delete(ctx, key)
+}
+
+func (t *timeoutService) List(ctx context.Context, prefix string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	return t.store.List(ctx, prefix)
+}
+
+func (t *timeoutService) Versions(ctx context.Context, key string) ([]Version, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	return t.store.Versions(ctx, key)
+}
+
+func (t *timeoutService) GetVersion(ctx context.Context, key, version string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	return t.store.GetVersion(ctx, key, version)
+}
+
+func (t *timeoutService) CheckWriteAccess(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	return t.store.CheckWriteAccess(ctx)
+}
+
+func (t *timeoutService) Test(ctx context.Context, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	return t.store.Test(ctx, key)
+}
@@ -0,0 +1,95 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package leader
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/klog/v2"
+	meta_util "kmodules.xyz/client-go/meta"
+)
+
+// Elector reports whether this process currently holds the leader lease.
+type Elector struct {
+	leading atomic.Bool
+}
+
+// Start joins the election for the Lease leaseName and keeps taking part in it
+// until the context is cancelled. The lease is released on cancellation.
+func Start(ctx context.Context, client kubernetes.Interface, opts *Options, leaseName string) (*Elector, error) {
+	e := &Elector{}
+	id := meta_util.PodName() + "_" + string(uuid.NewUUID())
+
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Name:      leaseName,
+			Namespace: opts.LeaseNamespace,
+		},
+		Client: client.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: id,
+		},
+	}
+
+	le, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   opts.LeaseDuration,
+		RenewDeadline:   opts.RenewDeadline,
+		RetryPeriod:     opts.RetryPeriod,
+		ReleaseOnCancel: true,
+		Name:            leaseName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				klog.Infof("%s acquired the lease %s/%s", id, opts.LeaseNamespace, leaseName)
+				e.leading.Store(true)
+			},
+			OnStoppedLeading: func() {
+				klog.Infof("%s lost the lease %s/%s", id, opts.LeaseNamespace, leaseName)
+				e.leading.Store(false)
+			},
+			OnNewLeader: func(identity string) {
+				if identity != id {
+					klog.Infof("%s is the leader", identity)
+				}
+			},
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create leader elector")
+	}
+
+	go func() {
+		// Run returns when the leadership is lost, keep competing for it
+		for ctx.Err() == nil {
+			le.Run(ctx)
+		}
+	}()
+
+	return e, nil
+}
+
+// IsLeader returns true if this process currently holds the lease.
+func (e *Elector) IsLeader() bool {
+	return e.leading.Load()
+}
@@ -0,0 +1,89 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package leader
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+	meta_util "kmodules.xyz/client-go/meta"
+)
+
+const (
+	LeaseDurationDefault = 15 * time.Second
+	RenewDeadlineDefault = 10 * time.Second
+	RetryPeriodDefault   = 2 * time.Second
+)
+
+type Options struct {
+	// enable leader election, only the leader initializes and configures vault
+	Enabled bool
+
+	// name and namespace of the Lease object used as lock,
+	// the name defaults to '<cluster-name>-vault-unsealer'
+	LeaseName      string
+	LeaseNamespace string
+
+	LeaseDuration time.Duration
+	RenewDeadline time.Duration
+	RetryPeriod   time.Duration
+
+	// if true, non-leaders do nothing at all instead of unsealing vault
+	StandBy bool
+}
+
+func NewOptions() *Options {
+	return &Options{
+		LeaseNamespace: meta_util.PodNamespace(),
+		LeaseDuration:  LeaseDurationDefault,
+		RenewDeadline:  RenewDeadlineDefault,
+		RetryPeriod:    RetryPeriodDefault,
+	}
+}
+
+func (o *Options) AddFlags(fs *pflag.FlagSet) {
+	fs.BoolVar(&o.Enabled, "leader-elect", o.Enabled, "Use a Kubernetes Lease to elect a leader among unsealer replicas, only the leader initializes and configures vault")
+	fs.StringVar(&o.LeaseName, "leader-elect.lease-name", o.LeaseName, "Name of the Lease object used for leader election. Defaults to '<cluster-name>-vault-unsealer'")
+	fs.StringVar(&o.LeaseNamespace, "leader-elect.lease-namespace", o.LeaseNamespace, "Namespace of the Lease object used for leader election")
+	fs.DurationVar(&o.LeaseDuration, "leader-elect.lease-duration", o.LeaseDuration, "Duration non-leaders wait before they try to acquire a lease that was not renewed")
+	fs.DurationVar(&o.RenewDeadline, "leader-elect.renew-deadline", o.RenewDeadline, "Duration the leader retries renewing the lease before it gives up leadership")
+	fs.DurationVar(&o.RetryPeriod, "leader-elect.retry-period", o.RetryPeriod, "Duration between two attempts to acquire or renew the lease")
+	fs.BoolVar(&o.StandBy, "leader-elect.standby", o.StandBy, "If true, non-leaders stand by and do nothing, otherwise they still unseal vault")
+}
+
+func (o *Options) Validate() []error {
+	if !o.Enabled {
+		return nil
+	}
+
+	var errs []error
+	if o.LeaseNamespace == "" {
+		errs = append(errs, errors.New("leader-elect.lease-namespace must be non empty"))
+	}
+	if o.LeaseDuration <= o.RenewDeadline {
+		errs = append(errs, errors.New("leader-elect.lease-duration must be greater than leader-elect.renew-deadline"))
+	}
+	if o.RetryPeriod <= 0 || o.RenewDeadline <= o.RetryPeriod {
+		errs = append(errs, errors.New("leader-elect.renew-deadline must be greater than leader-elect.retry-period, which must be positive"))
+	}
+	return errs
+}
+
+func (o *Options) Apply() error {
+	return nil
+}
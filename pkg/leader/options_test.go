@@ -0,0 +1,83 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package leader
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	aggregator "gomodules.xyz/errors"
+)
+
+func TestOptions_Validate(t *testing.T) {
+	testData := []struct {
+		testName    string
+		opts        *Options
+		expectedErr error
+	}{
+		{
+			"leader election disabled, validation successful",
+			&Options{},
+			nil,
+		},
+		{
+			"leader election enabled with defaults, validation successful",
+			&Options{
+				Enabled:        true,
+				LeaseNamespace: "default",
+				LeaseDuration:  LeaseDurationDefault,
+				RenewDeadline:  RenewDeadlineDefault,
+				RetryPeriod:    RetryPeriodDefault,
+			},
+			nil,
+		},
+		{
+			"lease duration <= renew deadline, validation failed",
+			&Options{
+				Enabled:        true,
+				LeaseNamespace: "default",
+				LeaseDuration:  10 * time.Second,
+				RenewDeadline:  10 * time.Second,
+				RetryPeriod:    RetryPeriodDefault,
+			},
+			errors.New("leader-elect.lease-duration must be greater than leader-elect.renew-deadline"),
+		},
+		{
+			"namespace is empty, validation failed",
+			&Options{
+				Enabled:       true,
+				LeaseDuration: LeaseDurationDefault,
+				RenewDeadline: RenewDeadlineDefault,
+				RetryPeriod:   RetryPeriodDefault,
+			},
+			errors.New("leader-elect.lease-namespace must be non empty"),
+		},
+	}
+
+	for _, test := range testData {
+		t.Run(test.testName, func(t *testing.T) {
+			errs := test.opts.Validate()
+			if test.expectedErr != nil {
+				assert.EqualError(t, aggregator.NewAggregate(errs), test.expectedErr.Error())
+			} else {
+				assert.Nil(t, errs)
+			}
+		})
+	}
+}
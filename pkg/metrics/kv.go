@@ -0,0 +1,121 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"kubevault.dev/unsealer/pkg/kv"
+)
+
+// instrumentedKV is an implementation of the kv.Service interface that records
+// the latency and the errors of every call made to the underlying store.
+type instrumentedKV struct {
+	store   kv.Service
+	metrics *Metrics
+	mode    string
+}
+
+var _ kv.Service = &instrumentedKV{}
+
+// InstrumentKV returns a kv.Service that records metrics for store, labeled
+// with the given key store mode.
+func (m *Metrics) InstrumentKV(store kv.Service, mode string) kv.Service {
+	return &instrumentedKV{
+		store:   store,
+		metrics: m,
+		mode:    mode,
+	}
+}
+
+func (i *instrumentedKV) Set(ctx context.Context, key string, value []byte) error {
+	start := time.Now()
+	err := i.store.Set(ctx, key, value)
+	i.metrics.observeKeyStore(i.mode, "set", start, err)
+	return err
+}
+
+func (i *instrumentedKV) Get(ctx context.Context, key string) ([]byte, error) {
+	start := time.Now()
+	value, err := i.store.Get(ctx, key)
+	// a missing key is an expected answer, not a failure of the key store
+	if _, ok := err.(*kv.NotFoundError); ok {
+		i.metrics.observeKeyStore(i.mode, "get", start, nil)
+	} else {
+		i.metrics.observeKeyStore(i.mode, "get", start, err)
+	}
+	return value, err
+}
+
+func (i *instrumentedKV) Create(ctx context.Context, key string, value []byte) error {
+	start := time.Now()
+	err := i.store.Create(ctx, key, value)
+	// an existing key is an expected answer, not a failure of the key store
+	if _, ok := err.(*kv.ExistsError); ok {
+		i.metrics.observeKeyStore(i.mode, "create", start, nil)
+	} else {
+		i.metrics.observeKeyStore(i.mode, "create", start, err)
+	}
+	return err
+}
+
+func (i *instrumentedKV) Delete(ctx context.Context, key string) error {
+	start := time.Now()
+	err := i.store.Delete(ctx, key)
+	i.metrics.observeKeyStore(i.mode, "delete", start, err)
+	return err
+}
+
+func (i *instrumentedKV) List(ctx context.Context, prefix string) ([]string, error) {
+	start := time.Now()
+	keys, err := i.store.List(ctx, prefix)
+	i.metrics.observeKeyStore(i.mode, "list", start, err)
+	return keys, err
+}
+
+func (i *instrumentedKV) Versions(ctx context.Context, key string) ([]kv.Version, error) {
+	start := time.Now()
+	versions, err := i.store.Versions(ctx, key)
+	i.metrics.observeKeyStore(i.mode, "versions", start, err)
+	return versions, err
+}
+
+func (i *instrumentedKV) GetVersion(ctx context.Context, key, version string) ([]byte, error) {
+	start := time.Now()
+	value, err := i.store.GetVersion(ctx, key, version)
+	if _, ok := err.(*kv.NotFoundError); ok {
+		i.metrics.observeKeyStore(i.mode, "get_version", start, nil)
+	} else {
+		i.metrics.observeKeyStore(i.mode, "get_version", start, err)
+	}
+	return value, err
+}
+
+func (i *instrumentedKV) CheckWriteAccess(ctx context.Context) error {
+	start := time.Now()
+	err := i.store.CheckWriteAccess(ctx)
+	i.metrics.observeKeyStore(i.mode, "check_write_access", start, err)
+	return err
+}
+
+func (i *instrumentedKV) Test(ctx context.Context, key string) error {
+	start := time.Now()
+	err := i.store.Test(ctx, key)
+	i.metrics.observeKeyStore(i.mode, "test", start, err)
+	return err
+}
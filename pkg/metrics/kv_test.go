@@ -0,0 +1,74 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+	"testing"
+
+	"kubevault.dev/unsealer/pkg/internal/kvtest"
+	"kubevault.dev/unsealer/pkg/kv"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingKV fails every call that changes the store
+type failingKV struct {
+	*kvtest.Store
+}
+
+func (f *failingKV) Set(ctx context.Context, key string, value []byte) error {
+	return errors.New("set failed")
+}
+
+// sampleCount returns how many key store calls were observed for operation
+func sampleCount(t *testing.T, m *Metrics, operation string) uint64 {
+	t.Helper()
+	var metric dto.Metric
+	require.NoError(t, m.keyStoreDuration.WithLabelValues("memory", operation).(prometheus.Histogram).Write(&metric))
+	return metric.GetHistogram().GetSampleCount()
+}
+
+func TestInstrumentKV(t *testing.T) {
+	m := New("vault")
+	store := m.InstrumentKV(&failingKV{Store: kvtest.New(map[string][]byte{"k": []byte("v")})}, "memory")
+	ctx := context.Background()
+
+	_, err := store.Get(ctx, "k")
+	require.NoError(t, err)
+	_, err = store.Get(ctx, "missing")
+	assert.IsType(t, &kv.NotFoundError{}, err)
+	err = store.Create(ctx, "k", []byte("v"))
+	assert.IsType(t, &kv.ExistsError{}, err)
+	assert.Error(t, store.Set(ctx, "k", []byte("v2")))
+	assert.Error(t, store.Set(ctx, "k", []byte("v3")))
+
+	assert.Equal(t, uint64(2), sampleCount(t, m, "get"))
+	assert.Equal(t, uint64(1), sampleCount(t, m, "create"))
+	assert.Equal(t, uint64(2), sampleCount(t, m, "set"))
+	assert.Equal(t, uint64(0), sampleCount(t, m, "delete"))
+
+	// missing and existing keys are answers of the key store, not failures
+	assert.Equal(t, float64(0), testutil.ToFloat64(m.keyStoreErrors.WithLabelValues("memory", "get")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(m.keyStoreErrors.WithLabelValues("memory", "create")))
+	assert.Equal(t, float64(2), testutil.ToFloat64(m.keyStoreErrors.WithLabelValues("memory", "set")))
+}
@@ -0,0 +1,195 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"math"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	namespace = "vault_unsealer"
+
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+// Metrics holds the prometheus collectors of the unsealer. Every metric is
+// labeled with the name of the cluster the unsealer belongs to.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	initAttempts      *prometheus.CounterVec
+	unsealAttempts    *prometheus.CounterVec
+	sealed            *prometheus.GaugeVec
+	up                *prometheus.GaugeVec
+	configureFailures prometheus.Counter
+	keyStoreDuration  *prometheus.HistogramVec
+	keyStoreErrors    *prometheus.CounterVec
+	snapshotAttempts  *prometheus.CounterVec
+	lastSnapshot      prometheus.Gauge
+
+	// unix nano timestamp of the last time a vault server was seen unsealed
+	lastUnsealed atomic.Int64
+}
+
+func New(clusterName string) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		initAttempts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "init_attempts_total",
+			Help:      "Number of attempts to initialize vault, partitioned by result.",
+		}, []string{"result"}),
+		unsealAttempts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "unseal_attempts_total",
+			Help:      "Number of attempts to unseal vault, partitioned by vault server and result.",
+		}, []string{"address", "result"}),
+		sealed: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "vault_sealed",
+			Help:      "Whether the vault server was sealed (1) or unsealed (0) when it was checked last.",
+		}, []string{"address"}),
+		up: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "vault_up",
+			Help:      "Whether the vault server answered (1) or not (0) when it was checked last.",
+		}, []string{"address"}),
+		configureFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "configure_failures_total",
+			Help:      "Number of failed attempts to configure vault auth and policies.",
+		}),
+		keyStoreDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "keystore_request_duration_seconds",
+			Help:      "Latency of key store calls, partitioned by mode and operation.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"mode", "operation"}),
+		keyStoreErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "keystore_errors_total",
+			Help:      "Number of failed key store calls, partitioned by mode and operation.",
+		}, []string{"mode", "operation"}),
+		snapshotAttempts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "snapshot_attempts_total",
+			Help:      "Number of attempts to take a raft snapshot and store it in the key store, partitioned by result.",
+		}, []string{"result"}),
+		lastSnapshot: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "last_snapshot_timestamp_seconds",
+			Help:      "Unix time of the last raft snapshot that was stored in the key store.",
+		}),
+	}
+	m.lastUnsealed.Store(time.Now().UnixNano())
+
+	reg := prometheus.WrapRegistererWith(prometheus.Labels{"cluster": clusterName}, m.registry)
+	reg.MustRegister(
+		m.initAttempts,
+		m.unsealAttempts,
+		m.sealed,
+		m.up,
+		m.configureFailures,
+		m.keyStoreDuration,
+		m.keyStoreErrors,
+		m.snapshotAttempts,
+		m.lastSnapshot,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "seconds_since_vault_unsealed",
+			Help:      "Seconds since a vault server was last seen unsealed, counted from the start of the unsealer until one is seen unsealed for the first time.",
+		}, func() float64 {
+			return math.Max(0, time.Since(time.Unix(0, m.lastUnsealed.Load())).Seconds())
+		}),
+	)
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
+	return m
+}
+
+// Handler returns the http handler that serves the metrics.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+func (m *Metrics) ObserveInit(err error) {
+	m.initAttempts.WithLabelValues(result(err)).Inc()
+}
+
+func (m *Metrics) ObserveUnseal(address string, err error) {
+	m.unsealAttempts.WithLabelValues(address, result(err)).Inc()
+}
+
+func (m *Metrics) ObserveSealed(address string, sealed bool) {
+	if sealed {
+		m.sealed.WithLabelValues(address).Set(1)
+		return
+	}
+	m.sealed.WithLabelValues(address).Set(0)
+	m.lastUnsealed.Store(time.Now().UnixNano())
+}
+
+func (m *Metrics) ObserveUp(address string, up bool) {
+	if up {
+		m.up.WithLabelValues(address).Set(1)
+		return
+	}
+	m.up.WithLabelValues(address).Set(0)
+}
+
+// ForgetServer drops the series of a vault server that is no longer managed.
+func (m *Metrics) ForgetServer(address string) {
+	m.unsealAttempts.DeletePartialMatch(prometheus.Labels{"address": address})
+	m.sealed.DeleteLabelValues(address)
+	m.up.DeleteLabelValues(address)
+}
+
+func (m *Metrics) ObserveConfigureFailure() {
+	m.configureFailures.Inc()
+}
+
+func (m *Metrics) ObserveSnapshot(err error) {
+	m.snapshotAttempts.WithLabelValues(result(err)).Inc()
+	if err == nil {
+		m.lastSnapshot.SetToCurrentTime()
+	}
+}
+
+func (m *Metrics) observeKeyStore(mode, operation string, start time.Time, err error) {
+	m.keyStoreDuration.WithLabelValues(mode, operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		m.keyStoreErrors.WithLabelValues(mode, operation).Inc()
+	}
+}
+
+func result(err error) string {
+	if err != nil {
+		return ResultFailure
+	}
+	return ResultSuccess
+}
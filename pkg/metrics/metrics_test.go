@@ -0,0 +1,95 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics_Attempts(t *testing.T) {
+	m := New("vault")
+
+	m.ObserveInit(nil)
+	m.ObserveInit(errors.New("failed"))
+	m.ObserveInit(errors.New("failed"))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.initAttempts.WithLabelValues(ResultSuccess)))
+	assert.Equal(t, float64(2), testutil.ToFloat64(m.initAttempts.WithLabelValues(ResultFailure)))
+
+	m.ObserveUnseal("https://vault-0:8200", nil)
+	m.ObserveUnseal("https://vault-1:8200", errors.New("failed"))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.unsealAttempts.WithLabelValues("https://vault-0:8200", ResultSuccess)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.unsealAttempts.WithLabelValues("https://vault-1:8200", ResultFailure)))
+
+	m.ObserveConfigureFailure()
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.configureFailures))
+
+	m.ObserveSnapshot(errors.New("failed"))
+	assert.Equal(t, float64(0), testutil.ToFloat64(m.lastSnapshot))
+	m.ObserveSnapshot(nil)
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.snapshotAttempts.WithLabelValues(ResultSuccess)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.snapshotAttempts.WithLabelValues(ResultFailure)))
+	assert.NotZero(t, testutil.ToFloat64(m.lastSnapshot))
+}
+
+func TestMetrics_ForgetServer(t *testing.T) {
+	m := New("vault")
+
+	for _, address := range []string{"https://vault-0:8200", "https://vault-1:8200"} {
+		m.ObserveUnseal(address, nil)
+		m.ObserveUnseal(address, errors.New("failed"))
+		m.ObserveSealed(address, true)
+		m.ObserveUp(address, true)
+	}
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.sealed.WithLabelValues("https://vault-0:8200")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.up.WithLabelValues("https://vault-0:8200")))
+
+	m.ForgetServer("https://vault-0:8200")
+	assert.Equal(t, 2, testutil.CollectAndCount(m.unsealAttempts))
+	assert.Equal(t, 1, testutil.CollectAndCount(m.sealed))
+	assert.Equal(t, 1, testutil.CollectAndCount(m.up))
+
+	m.ObserveSealed("https://vault-1:8200", false)
+	m.ObserveUp("https://vault-1:8200", false)
+	assert.Equal(t, float64(0), testutil.ToFloat64(m.sealed.WithLabelValues("https://vault-1:8200")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(m.up.WithLabelValues("https://vault-1:8200")))
+}
+
+func TestMetrics_ClusterLabel(t *testing.T) {
+	m := New("vault")
+	m.ObserveConfigureFailure()
+
+	n, err := testutil.GatherAndCount(m.registry, "vault_unsealer_configure_failures_total")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	families, err := m.registry.Gather()
+	assert.NoError(t, err)
+	for _, f := range families {
+		if f.GetName() != "vault_unsealer_configure_failures_total" {
+			continue
+		}
+		labels := f.GetMetric()[0].GetLabel()
+		if assert.Len(t, labels, 1) {
+			assert.Equal(t, "cluster", labels[0].GetName())
+			assert.Equal(t, "vault", labels[0].GetValue())
+		}
+	}
+}
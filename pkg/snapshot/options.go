@@ -0,0 +1,72 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package snapshot
+
+import (
+	"github.com/pkg/errors"
+	"github.com/robfig/cron/v3"
+	"github.com/spf13/pflag"
+)
+
+const (
+	RetentionDefault = 7
+	ChunkSizeDefault = 64 << 10
+)
+
+type Options struct {
+	// cron schedule of the raft snapshots, empty means no snapshots are taken
+	Schedule string
+
+	// number of snapshots that are kept, the oldest one is overwritten
+	Retention int
+
+	// size of the parts a snapshot is stored in, 0 means the default size of
+	// the key store
+	ChunkSize int
+}
+
+func NewOptions() *Options {
+	return &Options{
+		Retention: RetentionDefault,
+	}
+}
+
+func (o *Options) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.Schedule, "snapshot.schedule", o.Schedule, "Cron schedule of the raft snapshots that are stored in the key store, e.g. '0 */6 * * *'. No snapshots are taken if it is empty. The ssm and kubernetes-secret storages are too small for snapshots")
+	fs.IntVar(&o.Retention, "snapshot.retention", o.Retention, "Number of raft snapshots that are kept in the key store, the oldest one is overwritten by a new one")
+	fs.IntVar(&o.ChunkSize, "snapshot.chunk-size", o.ChunkSize, "Size in bytes of the parts a raft snapshot is stored in. 0 means the largest size the key store of the selected mode accepts")
+}
+
+func (o *Options) Validate() []error {
+	var errs []error
+	if o.Schedule != "" {
+		if _, err := cron.ParseStandard(o.Schedule); err != nil {
+			errs = append(errs, errors.Wrap(err, "invalid snapshot schedule"))
+		}
+	}
+	if o.Retention < 1 {
+		errs = append(errs, errors.New("snapshot retention must be at least 1"))
+	}
+	if o.ChunkSize < 0 {
+		errs = append(errs, errors.New("snapshot chunk size must not be negative"))
+	}
+	return errs
+}
+
+func (o *Options) Apply() error {
+	return nil
+}
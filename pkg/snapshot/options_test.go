@@ -0,0 +1,59 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package snapshot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	aggregator "gomodules.xyz/errors"
+)
+
+func TestOptions_Validate(t *testing.T) {
+	testData := []struct {
+		testName    string
+		opts        *Options
+		expectedErr string
+	}{
+		{
+			"invalid schedule, validation failed",
+			&Options{Schedule: "every hour", Retention: 1},
+			"invalid snapshot schedule: expected exactly 5 fields, found 2: [every hour]",
+		},
+		{
+			"retention is zero, validation failed",
+			&Options{Retention: 0},
+			"snapshot retention must be at least 1",
+		},
+		{
+			"validation successful",
+			&Options{Schedule: "0 */6 * * *", Retention: 7},
+			"",
+		},
+	}
+
+	for _, test := range testData {
+		t.Run(test.testName, func(t *testing.T) {
+			errs := test.opts.Validate()
+			if test.expectedErr != "" {
+				assert.EqualError(t, aggregator.NewAggregate(errs), test.expectedErr)
+			} else {
+				assert.Nil(t, errs)
+			}
+		})
+	}
+}
@@ -0,0 +1,256 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package snapshot
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"kubevault.dev/unsealer/pkg/kv"
+	"kubevault.dev/unsealer/pkg/vault/util"
+
+	"github.com/pkg/errors"
+)
+
+// Manifest describes a snapshot stored in a slot
+type Manifest struct {
+	Slot   int       `json:"slot"`
+	Time   time.Time `json:"time"`
+	Size   int64     `json:"size"`
+	Chunks int       `json:"chunks"`
+	SHA256 string    `json:"sha256"`
+	// the snapshot is written completely, the manifest of a slot that is
+	// being overwritten is not complete
+	Complete bool `json:"complete"`
+}
+
+// Store keeps raft snapshots in a key store. The key store may limit the size
+// of a value, so a snapshot is stored in chunks, described by a manifest. The
+// snapshots are kept in a fixed number of slots that are used as a ring
+// buffer, a new snapshot overwrites the oldest one.
+type Store struct {
+	keyStore  kv.Service
+	prefix    string
+	chunkSize int
+	retention int
+}
+
+// NewStore returns a Store that keeps retention snapshots in the key store,
+// ChunkSizeDefault is used if chunkSize is not positive.
+func NewStore(keyStore kv.Service, prefix string, chunkSize, retention int) *Store {
+	if chunkSize <= 0 {
+		chunkSize = ChunkSizeDefault
+	}
+	return &Store{
+		keyStore:  keyStore,
+		prefix:    prefix,
+		chunkSize: chunkSize,
+		retention: retention,
+	}
+}
+
+// List returns the complete snapshots, the newest first
+func (s *Store) List(ctx context.Context) ([]*Manifest, error) {
+	var list []*Manifest
+	for slot := 0; slot < s.retention; slot++ {
+		m, err := s.manifest(ctx, slot)
+		if err != nil {
+			return nil, err
+		}
+		if m != nil && m.Complete {
+			list = append(list, m)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Time.After(list[j].Time)
+	})
+	return list, nil
+}
+
+// manifest returns the manifest of the slot, nil if the slot is empty
+func (s *Store) manifest(ctx context.Context, slot int) (*Manifest, error) {
+	data, err := s.keyStore.Get(ctx, util.SnapshotID(s.prefix, slot))
+	if _, ok := err.(*kv.NotFoundError); ok {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "failed to get the manifest of snapshot %d", slot)
+	}
+
+	m := &Manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode the manifest of snapshot %d", slot)
+	}
+	return m, nil
+}
+
+func (s *Store) setManifest(ctx context.Context, m *Manifest) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if err := s.keyStore.Set(ctx, util.SnapshotID(s.prefix, m.Slot), data); err != nil {
+		return errors.Wrapf(err, "failed to store the manifest of snapshot %d", m.Slot)
+	}
+	return nil
+}
+
+// nextSlot returns the first empty or incomplete slot, or the slot of the
+// oldest snapshot
+func (s *Store) nextSlot(ctx context.Context) (int, error) {
+	next := -1
+	var oldest time.Time
+	for slot := 0; slot < s.retention; slot++ {
+		m, err := s.manifest(ctx, slot)
+		if err != nil {
+			return 0, err
+		}
+		if m == nil || !m.Complete {
+			return slot, nil
+		}
+		if next < 0 || m.Time.Before(oldest) {
+			next, oldest = slot, m.Time
+		}
+	}
+	return next, nil
+}
+
+// Save stores the snapshot that snapshot writes in the next slot. The slot is
+// marked incomplete before it is overwritten, so a failed save never leaves
+// a broken snapshot behind. The chunks of an earlier, larger snapshot in the
+// slot are deleted.
+func (s *Store) Save(ctx context.Context, snapshot func(w io.Writer) error) (*Manifest, error) {
+	slot, err := s.nextSlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{Slot: slot, Time: time.Now().UTC()}
+	if err := s.setManifest(ctx, m); err != nil {
+		return nil, err
+	}
+
+	w := &chunkWriter{ctx: ctx, store: s, slot: slot, hash: sha256.New()}
+	if err := snapshot(w); err != nil {
+		return nil, err
+	}
+	if err := w.flush(); err != nil {
+		return nil, err
+	}
+	if err := s.deleteChunks(ctx, slot, w.chunks); err != nil {
+		return nil, err
+	}
+
+	m.Size, m.Chunks, m.SHA256, m.Complete = w.size, w.chunks, hex.EncodeToString(w.hash.Sum(nil)), true
+	if err := s.setManifest(ctx, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// deleteChunks deletes the chunks of the slot from chunk from on. They are
+// listed, as a failed save leaves chunks behind that no manifest counts.
+func (s *Store) deleteChunks(ctx context.Context, slot, from int) error {
+	prefix := strings.TrimSuffix(util.SnapshotChunkID(s.prefix, slot, 0), "0")
+	keys, err := s.keyStore.List(ctx, prefix)
+	if err != nil {
+		return errors.Wrapf(err, "failed to list the chunks of snapshot %d", slot)
+	}
+	for _, key := range keys {
+		if i, err := strconv.Atoi(strings.TrimPrefix(key, prefix)); err != nil || i < from {
+			continue
+		}
+		if err := s.keyStore.Delete(ctx, key); err != nil {
+			return errors.Wrapf(err, "failed to delete the stale chunk %s of snapshot %d", key, slot)
+		}
+	}
+	return nil
+}
+
+// Load writes the snapshot of the manifest to w, it fails if the snapshot
+// does not match its checksum.
+func (s *Store) Load(ctx context.Context, m *Manifest, w io.Writer) error {
+	h := sha256.New()
+	var size int64
+	for i := 0; i < m.Chunks; i++ {
+		data, err := s.keyStore.Get(ctx, util.SnapshotChunkID(s.prefix, m.Slot, i))
+		if err != nil {
+			return errors.Wrapf(err, "failed to get chunk %d of snapshot %d", i, m.Slot)
+		}
+		h.Write(data)
+		size += int64(len(data))
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	if size != m.Size || hex.EncodeToString(h.Sum(nil)) != m.SHA256 {
+		return errors.Errorf("snapshot %d is corrupt, it does not match its checksum", m.Slot)
+	}
+	return nil
+}
+
+// chunkWriter stores everything written to it in chunks of the chunk size
+type chunkWriter struct {
+	ctx   context.Context
+	store *Store
+	slot  int
+
+	buf    []byte
+	chunks int
+	size   int64
+	hash   hash.Hash
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		free := w.store.chunkSize - len(w.buf)
+		if free > len(p) {
+			free = len(p)
+		}
+		w.buf = append(w.buf, p[:free]...)
+		p = p[free:]
+		if len(w.buf) == w.store.chunkSize {
+			if err := w.flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// flush stores the buffered data as the next chunk
+func (w *chunkWriter) flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	if err := w.store.keyStore.Set(w.ctx, util.SnapshotChunkID(w.store.prefix, w.slot, w.chunks), w.buf); err != nil {
+		return errors.Wrapf(err, "failed to store chunk %d of snapshot %d", w.chunks, w.slot)
+	}
+	w.hash.Write(w.buf)
+	w.chunks++
+	w.size += int64(len(w.buf))
+	w.buf = nil
+	return nil
+}
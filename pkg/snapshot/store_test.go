@@ -0,0 +1,123 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"kubevault.dev/unsealer/pkg/internal/kvtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func write(data string) func(w io.Writer) error {
+	return func(w io.Writer) error {
+		// vault streams the snapshot in pieces that do not match the chunks
+		for _, b := range []byte(data) {
+			if _, err := w.Write([]byte{b}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("snapshot is stored in chunks", func(t *testing.T) {
+		keyStore := kvtest.New(nil)
+		s := NewStore(keyStore, "vault", 4, 3)
+
+		m, err := s.Save(ctx, write("0123456789"))
+		require.NoError(t, err)
+		assert.Equal(t, 0, m.Slot)
+		assert.Equal(t, int64(10), m.Size)
+		assert.Equal(t, 3, m.Chunks)
+		assert.True(t, m.Complete)
+		assert.Equal(t, []byte("0123"), keyStore.Values()["vault-snapshot-0-chunk-0"])
+		assert.Equal(t, []byte("89"), keyStore.Values()["vault-snapshot-0-chunk-2"])
+
+		var out bytes.Buffer
+		require.NoError(t, s.Load(ctx, m, &out))
+		assert.Equal(t, "0123456789", out.String())
+	})
+
+	t.Run("oldest snapshot is overwritten", func(t *testing.T) {
+		s := NewStore(kvtest.New(nil), "vault", 4, 2)
+
+		for _, data := range []string{"first", "second", "third"} {
+			_, err := s.Save(ctx, write(data))
+			require.NoError(t, err)
+		}
+
+		list, err := s.List(ctx)
+		require.NoError(t, err)
+		require.Len(t, list, 2)
+		assert.Equal(t, 0, list[0].Slot, "the first slot is reused")
+		assert.Equal(t, 1, list[1].Slot)
+
+		var out bytes.Buffer
+		require.NoError(t, s.Load(ctx, list[0], &out))
+		assert.Equal(t, "third", out.String())
+	})
+
+	t.Run("chunks of a larger snapshot are deleted", func(t *testing.T) {
+		keyStore := kvtest.New(nil)
+		s := NewStore(keyStore, "vault", 4, 1)
+
+		_, err := s.Save(ctx, write("0123456789"))
+		require.NoError(t, err)
+		m, err := s.Save(ctx, write("abcde"))
+		require.NoError(t, err)
+		assert.Equal(t, 2, m.Chunks)
+
+		keys, err := keyStore.List(ctx, "vault-snapshot-0-chunk-")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"vault-snapshot-0-chunk-0", "vault-snapshot-0-chunk-1"}, keys)
+	})
+
+	t.Run("failed snapshot is not listed", func(t *testing.T) {
+		s := NewStore(kvtest.New(nil), "vault", 4, 2)
+
+		_, err := s.Save(ctx, func(w io.Writer) error {
+			_, _ = w.Write([]byte("partial snapshot"))
+			return errors.New("connection reset")
+		})
+		assert.Error(t, err)
+
+		list, err := s.List(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, list)
+	})
+
+	t.Run("corrupt snapshot is not loaded", func(t *testing.T) {
+		keyStore := kvtest.New(nil)
+		s := NewStore(keyStore, "vault", 4, 2)
+
+		m, err := s.Save(ctx, write("0123456789"))
+		require.NoError(t, err)
+		require.NoError(t, keyStore.Set(ctx, "vault-snapshot-0-chunk-1", []byte("xxxx")))
+
+		assert.ErrorContains(t, s.Load(ctx, m, io.Discard), "does not match its checksum")
+	})
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package auth
 
 import (
+	"context"
+
 	vaultapi "github.com/hashicorp/vault/api"
 	"github.com/pkg/errors"
 )
@@ -27,8 +29,8 @@ const (
 )
 
 type Authenticator interface {
-	EnsureAuth() error
-	ConfigureAuth() error
+	EnsureAuth(ctx context.Context) error
+	ConfigureAuth(ctx context.Context) error
 }
 
 type KubernetesAuthenticator struct {
@@ -45,12 +47,12 @@ func NewKubernetesAuthenticator(vc *vaultapi.Client, cf *K8sAuthenticatorOptions
 
 // EnsureAuth will ensure kubernetes auth
 // it's safe to call multiple times
-func (k *KubernetesAuthenticator) EnsureAuth() error {
+func (k *KubernetesAuthenticator) EnsureAuth(ctx context.Context) error {
 	if k.vc == nil {
 		return errors.New("vault client is nil")
 	}
 
-	authList, err := k.vc.Sys().ListAuth()
+	authList, err := k.vc.Sys().ListAuthWithContext(ctx)
 	if err != nil {
 		return err
 	}
@@ -61,7 +63,7 @@ func (k *KubernetesAuthenticator) EnsureAuth() error {
 		}
 	}
 
-	err = k.vc.Sys().EnableAuthWithOptions(kubernetesAuthPath, &vaultapi.EnableAuthOptions{
+	err = k.vc.Sys().EnableAuthWithOptionsWithContext(ctx, kubernetesAuthPath, &vaultapi.EnableAuthOptions{
 		Type: kubernetesAuthType,
 	})
 	return err
@@ -70,7 +72,7 @@ func (k *KubernetesAuthenticator) EnsureAuth() error {
 // links: https://www.vaultproject.io/api/auth/kubernetes/index.html#configure-method
 // ConfigureAuth will set the kubernetes config
 // it's safe to call multiple times
-func (k *KubernetesAuthenticator) ConfigureAuth() error {
+func (k *KubernetesAuthenticator) ConfigureAuth(ctx context.Context) error {
 	if k.vc == nil {
 		return errors.New("vault client is nil")
 	}
@@ -86,6 +88,6 @@ func (k *KubernetesAuthenticator) ConfigureAuth() error {
 		"disable_local_ca_jwt":   true,
 	}
 
-	_, err := k.vc.Logical().Write("auth/kubernetes/config", payload)
+	_, err := k.vc.Logical().WriteWithContext(ctx, "auth/kubernetes/config", payload)
 	return err
 }
@@ -17,6 +17,7 @@ limitations under the License.
 package auth
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -104,7 +105,7 @@ func TestEnsureKubernetesAuth2(t *testing.T) {
 			vc, err := vault.NewVaultClient(srv.URL, true, nil)
 			if assert.Nil(t, err) {
 				k := NewKubernetesAuthenticator(vc, nil)
-				err = k.EnsureAuth()
+				err = k.EnsureAuth(context.Background())
 				if c.expectErr {
 					assert.NotNil(t, err)
 				} else {
@@ -161,7 +162,7 @@ func TestConfigureKubernetesAuth2(t *testing.T) {
 			vc, err := vault.NewVaultClient(srv.URL, true, nil)
 			if assert.Nil(t, err) {
 				k := NewKubernetesAuthenticator(vc, &K8sAuthenticatorOptions{c.k8sHost, c.k8sCA, c.jwt})
-				err = k.ConfigureAuth()
+				err = k.ConfigureAuth(context.Background())
 				if c.expectErr {
 					assert.NotNil(t, err)
 				} else {
@@ -185,7 +186,7 @@ func TestEnsureKubernetesAuth(t *testing.T) {
 		return
 	}
 	k := NewKubernetesAuthenticator(vc, nil)
-	err = k.EnsureAuth()
+	err = k.EnsureAuth(context.Background())
 	assert.Nil(t, err)
 }
 
@@ -206,6 +207,6 @@ func TestConfigureKubernetesAuth(t *testing.T) {
 	}
 
 	k := NewKubernetesAuthenticator(vc, &K8sAuthenticatorOptions{k8sHOST, k8sCA, jwt})
-	err = k.ConfigureAuth()
+	err = k.ConfigureAuth(context.Background())
 	assert.Nil(t, err)
 }
@@ -17,6 +17,8 @@ limitations under the License.
 package policy
 
 import (
+	"strings"
+
 	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
 )
@@ -41,6 +43,10 @@ func (o *PolicyManagerOptions) Validate() []error {
 	var errs []error
 	if o.Name == "" {
 		errs = append(errs, errors.New("policy-manager.name must be non empty"))
+	} else if o.Name == UnsealerPolicyName {
+		errs = append(errs, errors.New("policy-manager.name must not be "+UnsealerPolicyName+", the policy of the unsealer"))
+	} else if strings.ContainsAny(o.Name, "*+/") {
+		errs = append(errs, errors.New("policy-manager.name must not contain '*', '+' or '/'"))
 	}
 	if o.ServiceAccountName == "" {
 		errs = append(errs, errors.New("policy-manager.service-account-name must be non empty"))
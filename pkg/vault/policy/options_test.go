@@ -48,6 +48,24 @@ func TestVaultOptions_Validate(t *testing.T) {
 			},
 			errors.New("policy-manager.name must be non empty"),
 		},
+		{
+			"name of the unsealer policy, validation failed",
+			&PolicyManagerOptions{
+				Name:                    "vault-unsealer",
+				ServiceAccountName:      "ok",
+				ServiceAccountNamespace: "ok",
+			},
+			errors.New("policy-manager.name must not be vault-unsealer, the policy of the unsealer"),
+		},
+		{
+			"name with a glob, validation failed",
+			&PolicyManagerOptions{
+				Name:                    "vault-*",
+				ServiceAccountName:      "ok",
+				ServiceAccountNamespace: "ok",
+			},
+			errors.New("policy-manager.name must not contain '*', '+' or '/'"),
+		},
 		{
 			"service account name is empty, validation failed",
 			&PolicyManagerOptions{
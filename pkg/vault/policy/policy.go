@@ -17,6 +17,7 @@ limitations under the License.
 package policy
 
 import (
+	"context"
 	"fmt"
 
 	vaultapi "github.com/hashicorp/vault/api"
@@ -82,10 +83,58 @@ path "sys/namespaces/*" {
 }
 `
 
+// UnsealerPolicyName is the name of the policy attached to the scoped admin
+// token of the unsealer
+const UnsealerPolicyName = "vault-unsealer"
+
+// policyUnsealer grants what configuring vault needs: enabling and
+// configuring kubernetes auth, and writing the policy and role of the policy
+// controller, whose name is filled in. It also grants taking raft snapshots.
+// It does not grant writing any other policy or role, the unsealer policy
+// included.
+const policyUnsealer = `
+path "sys/auth" {
+  capabilities = ["read"]
+}
+
+path "sys/auth/kubernetes" {
+  capabilities = ["create", "update", "sudo"]
+}
+
+path "auth/kubernetes/config" {
+  capabilities = ["create", "update", "read"]
+}
+
+path "sys/policies/acl/%[1]s" {
+  capabilities = ["create", "update", "read"]
+}
+
+path "auth/kubernetes/role/%[1]s" {
+  capabilities = ["create", "update", "read"]
+}
+
+path "sys/storage/raft/snapshot" {
+  capabilities = ["read"]
+}
+`
+
+// EnsureUnsealerPolicy writes the policy of the scoped admin token of the
+// unsealer, the name of the policy is UnsealerPolicyName. The token may only
+// write the policy and the role of the policy controller named name.
+func EnsureUnsealerPolicy(ctx context.Context, vc *vaultapi.Client, name string) error {
+	if vc == nil {
+		return errors.New("vault client is nil")
+	}
+	if name == UnsealerPolicyName {
+		return errors.Errorf("policy controller must not be named %s", UnsealerPolicyName)
+	}
+	return vc.Sys().PutPolicyWithContext(ctx, UnsealerPolicyName, fmt.Sprintf(policyUnsealer, name))
+}
+
 // EnsurePolicyAndPolicyBinding will ensure policy and kubernetes role
 // Name of the policy will be 'config.Name'
 // Name of the kubernetes role will be 'config.Name'
-func EnsurePolicyAndPolicyBinding(vc *vaultapi.Client, config *PolicyManagerOptions) error {
+func EnsurePolicyAndPolicyBinding(ctx context.Context, vc *vaultapi.Client, config *PolicyManagerOptions) error {
 	if vc == nil {
 		return errors.New("vault client is nil")
 	}
@@ -93,7 +142,7 @@ func EnsurePolicyAndPolicyBinding(vc *vaultapi.Client, config *PolicyManagerOpti
 		return errors.New("config is nil")
 	}
 
-	err := vc.Sys().PutPolicy(config.Name, policyAdmin)
+	err := vc.Sys().PutPolicyWithContext(ctx, config.Name, policyAdmin)
 	if err != nil {
 		return err
 	}
@@ -108,6 +157,6 @@ func EnsurePolicyAndPolicyBinding(vc *vaultapi.Client, config *PolicyManagerOpti
 		"period":                           "24h",
 	}
 
-	_, err = vc.Logical().Write(path, payload)
+	_, err = vc.Logical().WriteWithContext(ctx, path, payload)
 	return err
 }
@@ -17,6 +17,7 @@ limitations under the License.
 package policy
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -74,7 +75,7 @@ func TestEnsurePolicyAndPolicyBinding2(t *testing.T) {
 		t.Run(c.testName, func(t *testing.T) {
 			vc, err := vault.NewVaultClient(srv.URL, true, nil)
 			if assert.Nil(t, err) {
-				err = EnsurePolicyAndPolicyBinding(vc, &PolicyManagerOptions{c.name, c.saName, c.saNamespace})
+				err = EnsurePolicyAndPolicyBinding(context.Background(), vc, &PolicyManagerOptions{c.name, c.saName, c.saNamespace})
 				if c.expectErr {
 					assert.NotNil(t, err)
 				} else {
@@ -101,6 +102,6 @@ func TestConfigureKubernetesAuth(t *testing.T) {
 	}
 	vc.SetToken(token)
 
-	err = EnsurePolicyAndPolicyBinding(vc, &PolicyManagerOptions{policy, saName, saNamespace})
+	err = EnsurePolicyAndPolicyBinding(context.Background(), vc, &PolicyManagerOptions{policy, saName, saNamespace})
 	assert.Nil(t, err)
 }
@@ -0,0 +1,108 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package unseal
+
+import (
+	"context"
+	"encoding/base64"
+
+	"kubevault.dev/unsealer/pkg/kv"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+)
+
+// GenerateRootToken generates a new root token with the stored unseal keys,
+// or with the recovery keys if vault uses an auto-unseal seal. Vault encodes
+// the token with a one time password it generates, so the token is never
+// sent in plain text. Requires vault 1.10 or later.
+//
+// The token grants full privileges to vault, it has to be revoked after use.
+func (u *unsealer) GenerateRootToken(ctx context.Context) (string, error) {
+	if len(u.config.PGPKeys) > 0 {
+		return "", ErrKeysEncrypted
+	}
+
+	status, err := u.sealStatus(ctx)
+	if err != nil {
+		return "", err
+	}
+	recovery := status.Type != shamirSeal
+
+	gs, err := u.cl.Sys().GenerateRootStatusWithContext(ctx)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get the generate root status")
+	}
+	if gs.Started {
+		// it may be an operator's attempt, it has to be finished or cancelled
+		// by whoever started it
+		return "", ErrGenerateRootInProgress
+	}
+
+	gs, err = u.cl.Sys().GenerateRootInitWithContext(ctx, "", "")
+	if err != nil {
+		return "", errors.Wrap(err, "failed to start generating a root token")
+	}
+	if gs.OTP == "" {
+		_ = u.cl.Sys().GenerateRootCancelWithContext(ctx)
+		return "", errors.New("vault did not generate a one time password, vault 1.10 or later is required")
+	}
+	otp := gs.OTP
+
+	for i := 0; !gs.Complete; i++ {
+		keyID := u.shareID(i, recovery, false)
+		k, err := u.keyStore.Get(ctx, keyID)
+		if _, ok := err.(*kv.NotFoundError); ok {
+			err = errors.Errorf("failed to get key = %s with %s", keyID, err.Error())
+		} else if err != nil {
+			err = errors.Wrapf(ErrKeyStoreUnreachable, "failed to get key = %s with %s", keyID, err.Error())
+		} else {
+			gs, err = u.cl.Sys().GenerateRootUpdateWithContext(ctx, string(k), gs.Nonce)
+		}
+		if err != nil {
+			if cerr := u.cl.Sys().GenerateRootCancelWithContext(context.WithoutCancel(ctx)); cerr != nil {
+				klog.Errorf("failed to cancel the generate root attempt with %s", cerr.Error())
+			}
+			return "", errors.Wrap(err, "failed to generate a root token")
+		}
+	}
+
+	encoded := gs.EncodedToken
+	if encoded == "" {
+		encoded = gs.EncodedRootToken
+	}
+	return decodeRootToken(encoded, otp)
+}
+
+// decodeRootToken decodes a token that vault encoded with a one time
+// password: it is base64 encoded without padding, and xor-ed with the
+// password.
+func decodeRootToken(encoded, otp string) (string, error) {
+	data, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to decode the root token")
+	}
+	if len(data) != len(otp) {
+		return "", errors.New("failed to decode the root token, its length does not match the one time password")
+	}
+
+	token := make([]byte, len(data))
+	for i := range data {
+		token[i] = data[i] ^ otp[i]
+	}
+	return string(token), nil
+}
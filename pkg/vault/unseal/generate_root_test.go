@@ -0,0 +1,95 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package unseal
+
+import (
+	"context"
+	"testing"
+
+	"kubevault.dev/unsealer/pkg/internal/kvtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateRootToken(t *testing.T) {
+	const otp = "0123456789abcdefghijklmnopqrstuvwxyz"
+	const rootToken = "hvs.ABCDEFGHIJKLMNOPQRSTUVWXYZ012345"
+
+	newStore := func() *kvtest.Store {
+		return kvtest.New(map[string][]byte{
+			"vault-unseal-key-0":   []byte("k0"),
+			"vault-unseal-key-1":   []byte("k1"),
+			"vault-recovery-key-0": []byte("r0"),
+			"vault-recovery-key-1": []byte("r1"),
+		})
+	}
+
+	t.Run("token is generated with the unseal keys", func(t *testing.T) {
+		fv := &fakeVault{sealType: "shamir", threshold: 2, rootToken: rootToken, otp: otp}
+		u, err := New(newStore(), fv.client(t), UnsealOptions{KeyPrefix: "vault"})
+		require.NoError(t, err)
+
+		token, err := u.GenerateRootToken(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, rootToken, token)
+		assert.Equal(t, []string{"k0", "k1"}, fv.rootShares)
+		assert.False(t, fv.rootCanceled)
+	})
+
+	t.Run("token is generated with the recovery keys", func(t *testing.T) {
+		fv := &fakeVault{sealType: "awskms", threshold: 2, rootToken: rootToken, otp: otp}
+		u, err := New(newStore(), fv.client(t), UnsealOptions{KeyPrefix: "vault"})
+		require.NoError(t, err)
+
+		token, err := u.GenerateRootToken(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, rootToken, token)
+		assert.Equal(t, []string{"r0", "r1"}, fv.rootShares)
+	})
+
+	t.Run("attempt in progress is not cancelled", func(t *testing.T) {
+		fv := &fakeVault{sealType: "shamir", threshold: 2, rootToken: rootToken, otp: otp, rootInProgress: true}
+		u, err := New(newStore(), fv.client(t), UnsealOptions{KeyPrefix: "vault"})
+		require.NoError(t, err)
+
+		_, err = u.GenerateRootToken(context.Background())
+		assert.ErrorIs(t, err, ErrGenerateRootInProgress)
+		assert.False(t, fv.rootCanceled)
+		assert.Empty(t, fv.rootShares)
+	})
+
+	t.Run("attempt is cancelled if a key is missing", func(t *testing.T) {
+		fv := &fakeVault{sealType: "shamir", threshold: 3, rootToken: rootToken, otp: otp}
+		u, err := New(newStore(), fv.client(t), UnsealOptions{KeyPrefix: "vault"})
+		require.NoError(t, err)
+
+		_, err = u.GenerateRootToken(context.Background())
+		assert.Error(t, err)
+		assert.Equal(t, []string{"k0", "k1"}, fv.rootShares)
+		assert.True(t, fv.rootCanceled)
+	})
+
+	t.Run("encrypted keys can not be used", func(t *testing.T) {
+		fv := &fakeVault{sealType: "shamir", threshold: 2, rootToken: rootToken, otp: otp}
+		u, err := New(newStore(), fv.client(t), UnsealOptions{KeyPrefix: "vault", PGPKeys: []string{"key"}})
+		require.NoError(t, err)
+
+		_, err = u.GenerateRootToken(context.Background())
+		assert.ErrorIs(t, err, ErrKeysEncrypted)
+	})
+}
@@ -17,6 +17,10 @@ limitations under the License.
 package unseal
 
 import (
+	"encoding/pem"
+	"net/url"
+	"time"
+
 	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
 )
@@ -25,7 +29,8 @@ import (
 type UnsealOptions struct {
 	KeyPrefix string
 
-	// how many key parts exist
+	// how many key parts exist, they are recovery keys if vault uses an
+	// auto-unseal seal
 	SecretShares int
 	// how many of these parts are needed to unseal vault  (secretThreshold <= secretShares)
 	SecretThreshold int
@@ -36,27 +41,54 @@ type UnsealOptions struct {
 	// overwrite existing tokens
 	OverwriteExisting bool
 
+	// unseal vault with the migrate flag when it migrates from the shamir
+	// seal to an auto-unseal seal
+	SealMigration bool
+
+	// how long Unseal waits for a vault with an auto-unseal seal to unseal
+	// itself, zero means it is checked once
+	AutoUnsealTimeout time.Duration
+
 	// cluster name
 	ClusterName string
+
+	// base64 encoded PGP public keys, one per secret share, vault encrypts
+	// the unseal keys with them. Vault never returns the plaintext keys then,
+	// so it has to be unsealed by the key holders.
+	PGPKeys []string
+
+	// base64 encoded PGP public key, vault encrypts the root token with it
+	RootTokenPGPKey string
+
+	// base64 encoded PGP public keys, one per secret share. Each unseal key
+	// is also stored encrypted with one of them, so that the key holders can
+	// unseal vault if the key store can not be decrypted anymore.
+	CustodianPGPKeys []string
 }
 
 func NewUnsealOptions() *UnsealOptions {
 	return &UnsealOptions{
-		KeyPrefix:       "vault",
-		SecretThreshold: 3,
-		SecretShares:    5,
-		StoreRootToken:  true,
-		ClusterName:     "",
+		KeyPrefix:         "vault",
+		SecretThreshold:   3,
+		SecretShares:      5,
+		StoreRootToken:    true,
+		AutoUnsealTimeout: 30 * time.Second,
+		ClusterName:       "",
 	}
 }
 
 func (o *UnsealOptions) AddFlags(fs *pflag.FlagSet) {
 	fs.BoolVar(&o.StoreRootToken, "store-root-token", o.StoreRootToken, "should the root token be stored in the key store")
 	fs.BoolVar(&o.OverwriteExisting, "overwrite-existing", o.OverwriteExisting, "overwrite existing unseal keys and root tokens, possibly dangerous!")
-	fs.IntVar(&o.SecretShares, "secret-shares", o.SecretShares, "Total count of secret shares that exist")
-	fs.IntVar(&o.SecretThreshold, "secret-threshold", o.SecretThreshold, "Minimum required secret shares to unseal")
+	fs.BoolVar(&o.SealMigration, "seal-migration", o.SealMigration, "Migrate vault from the shamir seal to an auto-unseal seal, when vault is started in seal migration mode. The stored unseal keys are used with the migrate flag, and are moved to recovery keys afterwards")
+	fs.DurationVar(&o.AutoUnsealTimeout, "auto-unseal-timeout", o.AutoUnsealTimeout, "How long to wait for a vault with an auto-unseal seal to unseal itself before the unseal is retried. Zero means it is checked once")
+	fs.IntVar(&o.SecretShares, "secret-shares", o.SecretShares, "Total count of secret shares that exist, or of recovery shares if vault uses an auto-unseal seal")
+	fs.IntVar(&o.SecretThreshold, "secret-threshold", o.SecretThreshold, "Minimum required secret shares to unseal, or recovery shares to authorize operations if vault uses an auto-unseal seal")
 	fs.StringVar(&o.KeyPrefix, "key-prefix", o.KeyPrefix, "root token and unseal key prefix")
 	fs.StringVar(&o.ClusterName, "cluster-name", o.ClusterName, "cluster name")
+	fs.StringSliceVar(&o.PGPKeys, "pgp-keys", o.PGPKeys, "Base64 encoded PGP public keys, one per secret share. Vault encrypts the unseal or recovery keys with them, they are stored as custodian copies and vault has to be unsealed by the key holders")
+	fs.StringVar(&o.RootTokenPGPKey, "root-token-pgp-key", o.RootTokenPGPKey, "Base64 encoded PGP public key. Vault encrypts the root token with it, it is stored as custodian copy. Requires --store-root-token=false")
+	fs.StringSliceVar(&o.CustodianPGPKeys, "custodian-pgp-keys", o.CustodianPGPKeys, "Base64 encoded PGP public keys, one per secret share. Every unseal key is also stored encrypted with one of them, so that the key holders can recover vault")
 }
 
 func (o *UnsealOptions) Validate() []error {
@@ -70,12 +102,113 @@ func (o *UnsealOptions) Validate() []error {
 	if o.SecretThreshold > o.SecretShares {
 		errs = append(errs, errors.New("secret threshold must be less than or equal to secret shares"))
 	}
+	if o.AutoUnsealTimeout < 0 {
+		errs = append(errs, errors.New("auto-unseal-timeout must not be negative"))
+	}
 	if len(o.ClusterName) == 0 {
 		errs = append(errs, errors.New("cluster-name flag not set"))
 	}
+	if len(o.PGPKeys) > 0 && len(o.PGPKeys) != o.SecretShares {
+		errs = append(errs, errors.New("number of pgp keys must be equal to secret shares"))
+	}
+	if len(o.CustodianPGPKeys) > 0 && len(o.CustodianPGPKeys) != o.SecretShares {
+		errs = append(errs, errors.New("number of custodian pgp keys must be equal to secret shares"))
+	}
+	if len(o.PGPKeys) > 0 && len(o.CustodianPGPKeys) > 0 {
+		errs = append(errs, errors.New("pgp keys and custodian pgp keys can not be used together"))
+	}
+	if o.RootTokenPGPKey != "" && o.StoreRootToken {
+		errs = append(errs, errors.New("root token pgp key can not be used with store-root-token, vault only returns the encrypted root token"))
+	}
+	keys := append([]string{o.RootTokenPGPKey}, o.PGPKeys...)
+	keys = append(keys, o.CustodianPGPKeys...)
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if _, err := readPGPKey(key); err != nil {
+			errs = append(errs, err)
+		}
+	}
 	return errs
 }
 
 func (o *UnsealOptions) Apply() error {
 	return nil
 }
+
+// RekeyOptions holds the shares and threshold vault is rekeyed with
+type RekeyOptions struct {
+	// how many key parts exist after the rekey
+	SecretShares int
+	// how many of these parts are needed to unseal vault after the rekey
+	SecretThreshold int
+}
+
+func NewRekeyOptions() *RekeyOptions {
+	return &RekeyOptions{
+		SecretThreshold: 3,
+		SecretShares:    5,
+	}
+}
+
+func (o *RekeyOptions) AddFlags(fs *pflag.FlagSet) {
+	fs.IntVar(&o.SecretShares, "new-secret-shares", o.SecretShares, "Total count of secret shares after the rekey")
+	fs.IntVar(&o.SecretThreshold, "new-secret-threshold", o.SecretThreshold, "Minimum required secret shares to unseal after the rekey")
+}
+
+func (o *RekeyOptions) Validate() []error {
+	var errs []error
+	if o.SecretThreshold <= 0 {
+		errs = append(errs, errors.New("new secret threshold must be positive"))
+	}
+	if o.SecretShares <= 0 {
+		errs = append(errs, errors.New("new secret shares must be positive"))
+	}
+	if o.SecretThreshold > o.SecretShares {
+		errs = append(errs, errors.New("new secret threshold must be less than or equal to new secret shares"))
+	}
+	return errs
+}
+
+func (o *RekeyOptions) Apply() error {
+	return nil
+}
+
+// RaftOptions holds how vault servers that are not initialized join the raft
+// cluster of the initialized ones
+type RaftOptions struct {
+	// api address of the raft leader, empty means the address of an unsealed
+	// vault server
+	LeaderAPIAddress string
+	// PEM encoded CA cert used to verify the raft leader
+	LeaderCACert string
+}
+
+func NewRaftOptions() *RaftOptions {
+	return &RaftOptions{}
+}
+
+func (o *RaftOptions) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.LeaderAPIAddress, "raft.leader-api-address", o.LeaderAPIAddress, "API address of the raft leader that vault servers which are not initialized join. If not set, the address of an unsealed vault server is used")
+	fs.StringVar(&o.LeaderCACert, "raft.leader-ca-cert", o.LeaderCACert, "PEM encoded CA cert used by the joining vault servers to verify the raft leader")
+}
+
+func (o *RaftOptions) Validate() []error {
+	var errs []error
+	if o.LeaderAPIAddress != "" {
+		if u, err := url.Parse(o.LeaderAPIAddress); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, errors.New("raft.leader-api-address must be an url, e.g. https://vault-0.vault-internal:8200"))
+		}
+	}
+	if o.LeaderCACert != "" {
+		if block, _ := pem.Decode([]byte(o.LeaderCACert)); block == nil {
+			errs = append(errs, errors.New("raft.leader-ca-cert must be a PEM encoded certificate"))
+		}
+	}
+	return errs
+}
+
+func (o *RaftOptions) Apply() error {
+	return nil
+}
@@ -18,6 +18,7 @@ package unseal
 
 import (
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
@@ -54,6 +55,152 @@ func TestVaultOptions_Validate(t *testing.T) {
 			},
 			errors.New("cluster-name flag not set"),
 		},
+		{
+			"auto-unseal timeout is negative, validation failed",
+			&UnsealOptions{
+				SecretShares:      1,
+				SecretThreshold:   1,
+				ClusterName:       "vault",
+				AutoUnsealTimeout: -time.Second,
+			},
+			errors.New("auto-unseal-timeout must not be negative"),
+		},
+	}
+
+	_, key := newTestPGPKey(t)
+	testData = append(testData, []struct {
+		testName    string
+		opts        *UnsealOptions
+		expectedErr error
+	}{
+		{
+			"pgp keys do not match secret shares, validation failed",
+			&UnsealOptions{
+				SecretShares:    2,
+				SecretThreshold: 1,
+				ClusterName:     "vault",
+				PGPKeys:         []string{key},
+			},
+			errors.New("number of pgp keys must be equal to secret shares"),
+		},
+		{
+			"pgp keys with custodian pgp keys, validation failed",
+			&UnsealOptions{
+				SecretShares:     1,
+				SecretThreshold:  1,
+				ClusterName:      "vault",
+				PGPKeys:          []string{key},
+				CustodianPGPKeys: []string{key},
+			},
+			errors.New("pgp keys and custodian pgp keys can not be used together"),
+		},
+		{
+			"root token pgp key with store root token, validation failed",
+			&UnsealOptions{
+				SecretShares:    1,
+				SecretThreshold: 1,
+				ClusterName:     "vault",
+				StoreRootToken:  true,
+				RootTokenPGPKey: key,
+			},
+			errors.New("root token pgp key can not be used with store-root-token, vault only returns the encrypted root token"),
+		},
+		{
+			"invalid pgp key, validation failed",
+			&UnsealOptions{
+				SecretShares:     1,
+				SecretThreshold:  1,
+				ClusterName:      "vault",
+				CustodianPGPKeys: []string{"bm90IGEga2V5"},
+			},
+			errors.New("failed to read the pgp key: openpgp: invalid data: tag byte does not have MSB set"),
+		},
+		{
+			"pgp keys, validation successful",
+			&UnsealOptions{
+				SecretShares:     2,
+				SecretThreshold:  1,
+				ClusterName:      "vault",
+				CustodianPGPKeys: []string{key, key},
+				RootTokenPGPKey:  key,
+			},
+			nil,
+		},
+	}...)
+
+	for _, test := range testData {
+		t.Run(test.testName, func(t *testing.T) {
+			errs := test.opts.Validate()
+			if test.expectedErr != nil {
+				assert.EqualError(t, aggregator.NewAggregate(errs), test.expectedErr.Error())
+			} else {
+				assert.Nil(t, errs)
+			}
+		})
+	}
+}
+
+func TestRekeyOptions_Validate(t *testing.T) {
+	testData := []struct {
+		testName    string
+		opts        *RekeyOptions
+		expectedErr error
+	}{
+		{
+			"new secret threshold > new secret shares, validation failed",
+			&RekeyOptions{
+				SecretShares:    1,
+				SecretThreshold: 2,
+			},
+			errors.New("new secret threshold must be less than or equal to new secret shares"),
+		},
+		{
+			"validation successful",
+			NewRekeyOptions(),
+			nil,
+		},
+	}
+
+	for _, test := range testData {
+		t.Run(test.testName, func(t *testing.T) {
+			errs := test.opts.Validate()
+			if test.expectedErr != nil {
+				assert.EqualError(t, aggregator.NewAggregate(errs), test.expectedErr.Error())
+			} else {
+				assert.Nil(t, errs)
+			}
+		})
+	}
+}
+
+func TestRaftOptions_Validate(t *testing.T) {
+	testData := []struct {
+		testName    string
+		opts        *RaftOptions
+		expectedErr error
+	}{
+		{
+			"leader api address is not an url, validation failed",
+			&RaftOptions{
+				LeaderAPIAddress: "vault-0:8200",
+			},
+			errors.New("raft.leader-api-address must be an url, e.g. https://vault-0.vault-internal:8200"),
+		},
+		{
+			"leader ca cert is not PEM encoded, validation failed",
+			&RaftOptions{
+				LeaderCACert: "ca.crt",
+			},
+			errors.New("raft.leader-ca-cert must be a PEM encoded certificate"),
+		},
+		{
+			"validation successful",
+			&RaftOptions{
+				LeaderAPIAddress: "https://vault-0.vault-internal:8200",
+				LeaderCACert:     "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n",
+			},
+			nil,
+		},
 	}
 
 	for _, test := range testData {
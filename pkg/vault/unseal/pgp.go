@@ -0,0 +1,65 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package unseal
+
+import (
+	"bytes"
+	"encoding/base64"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/pkg/errors"
+)
+
+// readPGPKey reads a base64 encoded binary PGP public key, the format the
+// vault api expects.
+func readPGPKey(key string) (*openpgp.Entity, error) {
+	data, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode the base64 encoded pgp key")
+	}
+	entity, err := openpgp.ReadEntity(packet.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the pgp key")
+	}
+	return entity, nil
+}
+
+// encryptShare encrypts an unseal key with a PGP public key, the result is
+// base64 encoded like the keys vault encrypts itself, so that both are
+// decrypted the same way:
+//
+//	echo <encrypted key> | base64 -d | gpg -dq
+func encryptShare(share, key string) (string, error) {
+	entity, err := readPGPKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	w, err := openpgp.Encrypt(&buf, []*openpgp.Entity{entity}, nil, nil, nil)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to encrypt the unseal key")
+	}
+	if _, err := w.Write([]byte(share)); err != nil {
+		return "", errors.Wrap(err, "failed to encrypt the unseal key")
+	}
+	if err := w.Close(); err != nil {
+		return "", errors.Wrap(err, "failed to encrypt the unseal key")
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
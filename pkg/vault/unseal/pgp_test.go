@@ -0,0 +1,57 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package unseal
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPGPKey(t *testing.T) (*openpgp.Entity, string) {
+	t.Helper()
+
+	entity, err := openpgp.NewEntity("custodian", "", "custodian@example.com", nil)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, entity.Serialize(&buf))
+	return entity, base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestEncryptShare(t *testing.T) {
+	entity, key := newTestPGPKey(t)
+
+	encrypted, err := encryptShare("6f7a8b", key)
+	require.NoError(t, err)
+
+	data, err := base64.StdEncoding.DecodeString(encrypted)
+	require.NoError(t, err)
+	md, err := openpgp.ReadMessage(bytes.NewReader(data), openpgp.EntityList{entity}, nil, nil)
+	require.NoError(t, err)
+	share, err := io.ReadAll(md.UnverifiedBody)
+	require.NoError(t, err)
+	assert.Equal(t, "6f7a8b", string(share))
+
+	_, err = encryptShare("6f7a8b", "bm90IGEga2V5")
+	assert.Error(t, err, "not a pgp key")
+}
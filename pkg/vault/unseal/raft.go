@@ -0,0 +1,43 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package unseal
+
+import (
+	"context"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+)
+
+// JoinRaft joins vault to the raft cluster of the leader at leaderAPIAddr,
+// the leader is verified with leaderCACert if it is given. Vault is sealed
+// after it joined, it has to be unsealed with the keys of the cluster.
+func (u *unsealer) JoinRaft(ctx context.Context, leaderAPIAddr, leaderCACert string) error {
+	klog.Infof("joining the raft cluster of %s", leaderAPIAddr)
+	resp, err := u.cl.Sys().RaftJoinWithContext(ctx, &api.RaftJoinRequest{
+		LeaderAPIAddr: leaderAPIAddr,
+		LeaderCACert:  leaderCACert,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to join the raft cluster of %s", leaderAPIAddr)
+	}
+	if !resp.Joined {
+		return errors.Errorf("vault did not join the raft cluster of %s", leaderAPIAddr)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package unseal
+
+import (
+	"context"
+	"testing"
+
+	"kubevault.dev/unsealer/pkg/internal/kvtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJoinRaft(t *testing.T) {
+	fv := &fakeVault{sealType: "shamir"}
+	u, err := New(kvtest.New(nil), fv.client(t), UnsealOptions{KeyPrefix: "vault"})
+	require.NoError(t, err)
+
+	require.NoError(t, u.JoinRaft(context.Background(), "https://vault-0:8200", "ca"))
+	assert.Equal(t, "https://vault-0:8200", fv.joinReq.LeaderAPIAddr)
+	assert.Equal(t, "ca", fv.joinReq.LeaderCACert)
+
+	assert.Error(t, u.JoinRaft(context.Background(), "", ""), "vault did not join")
+}
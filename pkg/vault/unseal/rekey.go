@@ -0,0 +1,206 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package unseal
+
+import (
+	"context"
+
+	"kubevault.dev/unsealer/pkg/kv"
+	"kubevault.dev/unsealer/pkg/vault/util"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+)
+
+// Rekey replaces the unseal keys of vault with a new set of keys. The stored
+// keys authorize the rekey, vault only switches to the new keys once they are
+// verified, so the stored keys are left alone until then:
+//   - the new keys are stored under staging IDs
+//   - the new keys are verified, and vault uses them from then on
+//   - the new keys replace the stored keys, the keys beyond the new number of
+//     shares are deleted
+//   - the custodian copies are replaced, or deleted if no custodian pgp keys
+//     are set, they would decrypt to the old keys otherwise
+//   - the staged keys are deleted
+//
+// If storing or verifying the new keys fails, the rekey is cancelled and the
+// staged keys are deleted.
+func (u *unsealer) Rekey(ctx context.Context, opts RekeyOptions) error {
+	status, err := u.sealStatus(ctx)
+	if err != nil {
+		return err
+	}
+	switch {
+	case status.Type != shamirSeal:
+		return errors.Errorf("rekey of the recovery keys of the %s seal is not supported", status.Type)
+	case status.Sealed:
+		return errors.New("vault is sealed, it has to be unsealed before it is rekeyed")
+	case len(u.config.PGPKeys) > 0:
+		return ErrKeysEncrypted
+	case len(u.config.CustodianPGPKeys) > 0 && len(u.config.CustodianPGPKeys) != opts.SecretShares:
+		return errors.New("number of custodian pgp keys must be equal to the new secret shares")
+	}
+
+	rs, err := u.cl.Sys().RekeyStatusWithContext(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to get the rekey status")
+	}
+	if rs.Started {
+		return errors.New("a rekey is already in progress, it has to be cancelled first")
+	}
+
+	oldKeys, err := u.storedKeys(ctx)
+	if err != nil {
+		return err
+	}
+	if len(oldKeys) < status.T {
+		return errors.Errorf("only %d of the %d required unseal keys are stored", len(oldKeys), status.T)
+	}
+
+	rs, err = u.cl.Sys().RekeyInitWithContext(ctx, &api.RekeyInitRequest{
+		SecretShares:        opts.SecretShares,
+		SecretThreshold:     opts.SecretThreshold,
+		RequireVerification: true,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to start the rekey")
+	}
+	klog.Infof("started the rekey to %d shares with a threshold of %d", opts.SecretShares, opts.SecretThreshold)
+
+	// vault keeps using the current keys until the new ones are verified, so
+	// the rekey can be cancelled until then
+	ctx = context.WithoutCancel(ctx)
+
+	var update *api.RekeyUpdateResponse
+	for _, k := range oldKeys {
+		update, err = u.cl.Sys().RekeyUpdateWithContext(ctx, string(k), rs.Nonce)
+		if err != nil {
+			return u.cancelRekey(ctx, errors.Wrap(err, "failed to send the unseal key to the rekey"))
+		}
+		if update.Complete {
+			break
+		}
+	}
+	if update == nil || !update.Complete {
+		return u.cancelRekey(ctx, errors.New("the stored unseal keys did not complete the rekey"))
+	}
+
+	for i, k := range update.Keys {
+		keyID := util.StagedUnsealKeyID(u.config.KeyPrefix, i)
+		if err := u.keyStore.Set(ctx, keyID, []byte(k)); err != nil {
+			return u.discardStagedKeys(ctx, len(update.Keys), errors.Wrapf(ErrKeyStoreUnreachable, "failed to stage the new unseal key = %s with %s", keyID, err.Error()))
+		}
+	}
+	klog.Infof("staged %d new unseal keys, verifying them", len(update.Keys))
+
+	verified := false
+	for _, k := range update.Keys {
+		v, err := u.cl.Sys().RekeyVerificationUpdateWithContext(ctx, k, update.VerificationNonce)
+		if err != nil {
+			return u.discardStagedKeys(ctx, len(update.Keys), errors.Wrap(err, "failed to verify the new unseal keys"))
+		}
+		if v.Complete {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return u.discardStagedKeys(ctx, len(update.Keys), errors.New("the new unseal keys did not complete the verification"))
+	}
+	klog.Infoln("vault is rekeyed, it uses the new unseal keys")
+
+	for i, k := range update.Keys {
+		keyID := util.UnsealKeyID(u.config.KeyPrefix, i)
+		if err := u.keyStore.Set(ctx, keyID, []byte(k)); err != nil {
+			return errors.Wrapf(ErrKeyStoreUnreachable, "vault is rekeyed, but failed to store the new unseal key = %s with %s, the new keys are kept as %s",
+				keyID, err.Error(), util.StagedUnsealKeyID(u.config.KeyPrefix, i))
+		}
+	}
+	if err := u.deleteKeys(ctx, len(update.Keys), len(oldKeys), util.UnsealKeyID, util.CustodianUnsealKeyID); err != nil {
+		return errors.Wrap(err, "vault is rekeyed, but failed to delete the old unseal keys beyond the new shares")
+	}
+	klog.Infof("stored %d new unseal keys", len(update.Keys))
+
+	if len(u.config.CustodianPGPKeys) == 0 {
+		if err := u.deleteKeys(ctx, 0, len(update.Keys), util.CustodianUnsealKeyID); err != nil {
+			return errors.Wrap(err, "vault is rekeyed, but failed to delete the custodian copies of the old unseal keys")
+		}
+	}
+	for i, key := range u.config.CustodianPGPKeys {
+		keyID := util.CustodianUnsealKeyID(u.config.KeyPrefix, i)
+		encrypted, err := encryptShare(update.Keys[i], key)
+		if err == nil {
+			err = u.keyStore.Set(ctx, keyID, []byte(encrypted))
+		}
+		if err != nil {
+			return errors.Wrapf(err, "vault is rekeyed, but failed to store the custodian copy = %s", keyID)
+		}
+	}
+
+	if err := u.deleteKeys(ctx, 0, len(update.Keys), util.StagedUnsealKeyID); err != nil {
+		return errors.Wrap(err, "vault is rekeyed, but failed to delete the staged unseal keys")
+	}
+	return nil
+}
+
+// deleteKeys deletes the keys from index from up to index to of every kind
+func (u *unsealer) deleteKeys(ctx context.Context, from, to int, kinds ...func(prefix string, i int) string) error {
+	for i := from; i < to; i++ {
+		for _, id := range kinds {
+			keyID := id(u.config.KeyPrefix, i)
+			if err := u.keyStore.Delete(ctx, keyID); err != nil {
+				return errors.Wrapf(err, "failed to delete key = %s", keyID)
+			}
+		}
+	}
+	return nil
+}
+
+// storedKeys returns the unseal keys in the key store
+func (u *unsealer) storedKeys(ctx context.Context) ([][]byte, error) {
+	var keys [][]byte
+	for i := 0; ; i++ {
+		keyID := util.UnsealKeyID(u.config.KeyPrefix, i)
+		k, err := u.keyStore.Get(ctx, keyID)
+		if _, ok := err.(*kv.NotFoundError); ok {
+			return keys, nil
+		} else if err != nil {
+			return nil, errors.Wrapf(ErrKeyStoreUnreachable, "failed to get key = %s with %s", keyID, err.Error())
+		}
+		keys = append(keys, k)
+	}
+}
+
+// cancelRekey cancels the rekey in progress, vault keeps its current keys
+func (u *unsealer) cancelRekey(ctx context.Context, cause error) error {
+	if err := u.cl.Sys().RekeyCancelWithContext(ctx); err != nil {
+		klog.Errorf("failed to cancel the rekey with %s", err.Error())
+	} else {
+		klog.Infoln("cancelled the rekey, vault keeps its current unseal keys")
+	}
+	return cause
+}
+
+// discardStagedKeys deletes the staged keys and cancels the rekey, the
+// stored keys were not touched
+func (u *unsealer) discardStagedKeys(ctx context.Context, newShares int, cause error) error {
+	if err := u.deleteKeys(ctx, 0, newShares, util.StagedUnsealKeyID); err != nil {
+		klog.Errorf("failed to delete the staged unseal keys with %s", err.Error())
+	}
+	return u.cancelRekey(ctx, cause)
+}
@@ -0,0 +1,133 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package unseal
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"kubevault.dev/unsealer/pkg/internal/kvtest"
+	"kubevault.dev/unsealer/pkg/vault/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingKV fails to store the given key
+type failingKV struct {
+	*kvtest.Store
+	failKey string
+}
+
+func (f *failingKV) Set(ctx context.Context, key string, data []byte) error {
+	if key == f.failKey {
+		return errors.New("access denied")
+	}
+	return f.Store.Set(ctx, key, data)
+}
+
+func TestRekey(t *testing.T) {
+	newStore := func() *kvtest.Store {
+		return kvtest.New(map[string][]byte{
+			"vault-unseal-key-0": []byte("k0"),
+			"vault-unseal-key-1": []byte("k1"),
+			"vault-unseal-key-2": []byte("k2"),
+		})
+	}
+	opts := RekeyOptions{SecretShares: 2, SecretThreshold: 2}
+
+	t.Run("new keys are stored and verified", func(t *testing.T) {
+		fv := &fakeVault{sealType: "shamir", threshold: 2, newKeys: []string{"n0", "n1"}}
+		store := newStore()
+		u, err := New(store, fv.client(t), UnsealOptions{KeyPrefix: "vault"})
+		require.NoError(t, err)
+
+		require.NoError(t, u.Rekey(context.Background(), opts))
+		assert.Equal(t, 2, fv.rekeyReq.SecretShares)
+		assert.True(t, fv.rekeyReq.RequireVerification)
+		assert.Equal(t, []string{"k0", "k1"}, fv.rekeyShares)
+		assert.Equal(t, []string{"n0", "n1"}, fv.verifyShares)
+		assert.False(t, fv.rekeyCanceled)
+		assert.Equal(t, map[string][]byte{
+			"vault-unseal-key-0": []byte("n0"),
+			"vault-unseal-key-1": []byte("n1"),
+		}, store.Values(), "the old key beyond the new shares and the staged keys are deleted")
+	})
+
+	t.Run("custodian copies of the old keys are deleted", func(t *testing.T) {
+		fv := &fakeVault{sealType: "shamir", threshold: 2, newKeys: []string{"n0", "n1"}}
+		store := newStore()
+		for i := range 3 {
+			require.NoError(t, store.Set(context.Background(), util.CustodianUnsealKeyID("vault", i), []byte("encrypted")))
+		}
+		u, err := New(store, fv.client(t), UnsealOptions{KeyPrefix: "vault"})
+		require.NoError(t, err)
+
+		require.NoError(t, u.Rekey(context.Background(), opts))
+		assert.Equal(t, map[string][]byte{
+			"vault-unseal-key-0": []byte("n0"),
+			"vault-unseal-key-1": []byte("n1"),
+		}, store.Values())
+	})
+
+	t.Run("stored keys are untouched if the new keys can not be staged", func(t *testing.T) {
+		fv := &fakeVault{sealType: "shamir", threshold: 2, newKeys: []string{"n0", "n1"}}
+		store := &failingKV{Store: newStore(), failKey: "vault-staged-unseal-key-1"}
+		u, err := New(store, fv.client(t), UnsealOptions{KeyPrefix: "vault"})
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, u.Rekey(context.Background(), opts), ErrKeyStoreUnreachable)
+		assert.True(t, fv.rekeyCanceled)
+		assert.Empty(t, fv.verifyShares)
+		assert.Equal(t, newStore().Values(), store.Values())
+	})
+
+	t.Run("stored keys are untouched if the new keys are not verified", func(t *testing.T) {
+		fv := &fakeVault{sealType: "shamir", threshold: 2, newKeys: []string{"n0", "n1", "n2", "n3"}}
+		store := newStore()
+		u, err := New(store, fv.client(t), UnsealOptions{KeyPrefix: "vault"})
+		require.NoError(t, err)
+
+		// the new keys never reach the threshold of the verification
+		assert.Error(t, u.Rekey(context.Background(), RekeyOptions{SecretShares: 4, SecretThreshold: 5}))
+		assert.True(t, fv.rekeyCanceled)
+		assert.Equal(t, newStore().Values(), store.Values())
+	})
+
+	t.Run("new keys stay staged if they can not be stored once verified", func(t *testing.T) {
+		fv := &fakeVault{sealType: "shamir", threshold: 2, newKeys: []string{"n0", "n1"}}
+		store := &failingKV{Store: newStore(), failKey: "vault-unseal-key-1"}
+		u, err := New(store, fv.client(t), UnsealOptions{KeyPrefix: "vault"})
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, u.Rekey(context.Background(), opts), ErrKeyStoreUnreachable)
+		assert.False(t, fv.rekeyCanceled)
+		values := store.Values()
+		assert.Equal(t, []byte("n0"), values["vault-staged-unseal-key-0"])
+		assert.Equal(t, []byte("n1"), values["vault-staged-unseal-key-1"])
+	})
+
+	t.Run("recovery keys are not rekeyed", func(t *testing.T) {
+		fv := &fakeVault{sealType: "awskms"}
+		u, err := New(newStore(), fv.client(t), UnsealOptions{KeyPrefix: "vault"})
+		require.NoError(t, err)
+
+		assert.Error(t, u.Rekey(context.Background(), opts))
+		assert.Empty(t, fv.rekeyShares)
+	})
+}
@@ -17,7 +17,12 @@ limitations under the License.
 package unseal
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"net/http"
+	"strings"
+	"time"
 
 	"kubevault.dev/unsealer/pkg/kv"
 	"kubevault.dev/unsealer/pkg/vault/util"
@@ -37,14 +42,105 @@ type unsealer struct {
 
 var _ Unsealer = &unsealer{}
 
+var (
+	// ErrKeysNotStored is returned by Init when vault was initialized, but the
+	// unseal keys or the root token could not be written to the key store. Vault
+	// never hands out these keys again, so this can not be fixed by retrying.
+	ErrKeysNotStored = errors.New("vault is initialized, but its keys could not be stored")
+
+	// ErrKeyExists is returned by Init when the key store already holds keys
+	// for the key prefix and overwriting them is not allowed.
+	ErrKeyExists = errors.New("key already exists in the key store")
+
+	// ErrKeyStoreUnreachable is returned when a call to the key store failed
+	// for any other reason than a missing key.
+	ErrKeyStoreUnreachable = errors.New("key store is unreachable")
+
+	// ErrKeysEncrypted is returned by Unseal when vault encrypted the unseal
+	// keys with pgp keys, only the key holders can unseal it.
+	ErrKeysEncrypted = errors.New("unseal keys are pgp encrypted, vault has to be unsealed by the key holders")
+
+	// ErrAutoUnsealPending is returned by Unseal when vault uses an
+	// auto-unseal seal, but did not unseal itself in time.
+	ErrAutoUnsealPending = errors.New("vault unseals itself with its auto-unseal seal, but it is still sealed")
+
+	// ErrSealMigrationDisabled is returned by Unseal when vault migrates its
+	// seal, but seal migration is not enabled.
+	ErrSealMigrationDisabled = errors.New("vault is migrating its seal, but seal migration is not enabled")
+
+	// ErrGenerateRootInProgress is returned by GenerateRootToken when another
+	// generate root attempt is in progress. It may belong to an operator, so it
+	// is never cancelled by the unsealer.
+	ErrGenerateRootInProgress = errors.New("a generate root attempt is already in progress")
+)
+
+// UnsealKeysError is returned by Unseal when the stored unseal keys did not
+// unseal vault, it reports which keys could not be used.
+type UnsealKeysError struct {
+	// keys that are not found in the key store
+	Missing []string
+	// keys that could not be read from the key store
+	Unreachable []string
+	// keys that vault rejected
+	Rejected []string
+	// keys that vault failed to combine, one of them is most likely corrupt
+	Combined []string
+	// number of combinations of the keys that were tried
+	Combinations int
+}
+
+func (e *UnsealKeysError) Error() string {
+	msg := "failed to unseal the vault with the stored keys"
+	if len(e.Missing) > 0 {
+		msg += fmt.Sprintf(", missing keys %s", strings.Join(e.Missing, ", "))
+	}
+	if len(e.Unreachable) > 0 {
+		msg += fmt.Sprintf(", failed to get keys %s", strings.Join(e.Unreachable, ", "))
+	}
+	if len(e.Rejected) > 0 {
+		msg += fmt.Sprintf(", vault rejected keys %s", strings.Join(e.Rejected, ", "))
+	}
+	if len(e.Combined) > 0 {
+		msg += fmt.Sprintf(", vault failed to combine %d combinations of keys %s", e.Combinations, strings.Join(e.Combined, ", "))
+	}
+	return msg
+}
+
+// Unwrap reports the error as ErrKeyStoreUnreachable if a key could not be
+// read, the unseal may succeed once the key store is reachable again.
+func (e *UnsealKeysError) Unwrap() error {
+	if len(e.Unreachable) > 0 {
+		return ErrKeyStoreUnreachable
+	}
+	return nil
+}
+
+const (
+	// shamirSeal is the seal type of a vault that is unsealed with unseal keys,
+	// any other seal type unseals vault by itself and uses recovery keys.
+	shamirSeal = "shamir"
+
+	// how often Unseal checks whether a vault with an auto-unseal seal
+	// unsealed itself
+	autoUnsealPoll = time.Second
+
+	// how many other combinations of the unseal keys are tried once vault
+	// failed to combine them
+	maxUnsealCombinations = 10
+)
+
 // Unsealer is an interface that can be used to attempt to perform actions against
 // a Vault server.
 type Unsealer interface {
-	IsSealed() (bool, error)
-	IsInitialized() (bool, error)
-	Unseal() error
-	Init() error
-	CheckReadWriteAccess() error
+	IsSealed(ctx context.Context) (bool, error)
+	IsInitialized(ctx context.Context) (bool, error)
+	Unseal(ctx context.Context) error
+	Init(ctx context.Context) error
+	CheckReadWriteAccess(ctx context.Context) error
+	Rekey(ctx context.Context, opts RekeyOptions) error
+	GenerateRootToken(ctx context.Context) (string, error)
+	JoinRaft(ctx context.Context, leaderAPIAddr, leaderCACert string) error
+	FinishSealMigration(ctx context.Context) error
 }
 
 // New returns a new Unsealer, or an error.
@@ -56,16 +152,41 @@ func New(k kv.Service, cl *api.Client, config UnsealOptions) (Unsealer, error) {
 	}, nil
 }
 
-func (u *unsealer) IsSealed() (bool, error) {
-	resp, err := u.cl.Sys().SealStatus()
+func (u *unsealer) IsSealed(ctx context.Context) (bool, error) {
+	resp, err := u.cl.Sys().SealStatusWithContext(ctx)
 	if err != nil {
 		return false, fmt.Errorf("failed to check seal status with %s", err.Error())
 	}
 	return resp.Sealed, nil
 }
 
-func (u *unsealer) IsInitialized() (bool, error) {
-	resp, err := u.cl.Sys().InitStatus()
+// sealStatus returns the seal status of vault, its type is shamir or the
+// type of an auto-unseal seal, e.g. awskms
+func (u *unsealer) sealStatus(ctx context.Context) (*api.SealStatusResponse, error) {
+	resp, err := u.cl.Sys().SealStatusWithContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check seal status with %s", err.Error())
+	}
+	return resp, nil
+}
+
+// shareID returns the key name of the i-th unseal or recovery key, or of its
+// pgp encrypted custodian copy.
+func (u *unsealer) shareID(i int, recovery, custodian bool) string {
+	switch {
+	case recovery && custodian:
+		return util.CustodianRecoveryKeyID(u.config.KeyPrefix, i)
+	case recovery:
+		return util.RecoveryKeyID(u.config.KeyPrefix, i)
+	case custodian:
+		return util.CustodianUnsealKeyID(u.config.KeyPrefix, i)
+	default:
+		return util.UnsealKeyID(u.config.KeyPrefix, i)
+	}
+}
+
+func (u *unsealer) IsInitialized(ctx context.Context) (bool, error) {
+	resp, err := u.cl.Sys().InitStatusWithContext(ctx)
 	if err != nil {
 		return false, fmt.Errorf("failed to check initilized status with %s", err.Error())
 	}
@@ -76,59 +197,355 @@ func (u *unsealer) IsInitialized() (bool, error) {
 // and sending unseal requests to vault. It will return an error if retrieving
 // a key fails, or if the unseal progress is reset to 0 (indicating that a key)
 // was invalid.
-func (u *unsealer) Unseal() error {
-	for i := 0; ; i++ {
+//
+// A vault with an auto-unseal seal unseals itself, Unseal only waits for it.
+// A vault that migrates from the shamir seal to an auto-unseal seal is
+// unsealed with the migrate flag, if seal migration is enabled.
+func (u *unsealer) Unseal(ctx context.Context) error {
+	status, err := u.sealStatus(ctx)
+	if err != nil {
+		return err
+	}
+	if status.Migration {
+		return u.migrateSeal(ctx, status)
+	}
+	if status.Type != shamirSeal {
+		return u.waitForAutoUnseal(ctx, status.Type)
+	}
+
+	if len(u.config.PGPKeys) > 0 {
+		return ErrKeysEncrypted
+	}
+
+	return u.unsealWithKeys(ctx, false, status)
+}
+
+// share is an unseal key read from the key store
+type share struct {
+	id  string
+	key string
+}
+
+// unsealWithKeys sends the stored unseal keys to vault until it is unsealed.
+// The keys may be placed in several stores; keys that are missing, can not be
+// read or are rejected by vault are skipped as long as the others meet the
+// threshold. If vault fails to combine the keys, which happens when a key is
+// corrupt, the unseal progress is reset and other combinations of the keys
+// are tried.
+//
+// The number of keys is taken from the seal status, if vault does not report
+// it the keys are read until one is not found. Keys sent before, e.g. by an
+// unsealer that was stopped, are discarded first, they would be counted
+// towards the threshold otherwise.
+func (u *unsealer) unsealWithKeys(ctx context.Context, migrate bool, status *api.SealStatusResponse) error {
+	if status.Progress > 0 {
+		klog.Warningf("resetting the unseal progress of %d keys sent before", status.Progress)
+		if _, err := u.cl.Sys().UnsealWithOptionsWithContext(ctx, &api.UnsealOpts{Reset: true}); err != nil {
+			return fmt.Errorf("failed to reset the unseal progress with %s", err.Error())
+		}
+	}
+
+	report := &UnsealKeysError{}
+	// the keys that vault accepted, and once vault failed to combine them,
+	// every other key that is read
+	var keys []share
+	combine := false
+	for i := 0; status.N == 0 || i < status.N; i++ {
 		keyID := util.UnsealKeyID(u.config.KeyPrefix, i)
 
 		klog.Infof("try to retrieve key with keyID = %s, from kms service", keyID)
-		k, err := u.keyStore.Get(keyID)
-		if err != nil {
-			return fmt.Errorf("failed to get key = %s with %s", keyID, err.Error())
+		k, err := u.keyStore.Get(ctx, keyID)
+		if _, ok := err.(*kv.NotFoundError); ok {
+			report.Missing = append(report.Missing, keyID)
+			if status.N == 0 {
+				break
+			}
+			klog.Warningf("skipping key = %s, it is not found", keyID)
+			continue
+		} else if err != nil {
+			klog.Warningf("skipping key = %s, failed to get it with %s", keyID, err.Error())
+			report.Unreachable = append(report.Unreachable, keyID)
+			continue
+		}
+		s := share{id: keyID, key: string(k)}
+		if combine {
+			keys = append(keys, s)
+			continue
 		}
 
-		klog.Infof("try to send unseal request to the vault with keyID = %s", keyID)
-		resp, err := u.cl.Sys().Unseal(string(k))
-		if err != nil {
-			return fmt.Errorf("failed to send unseal request to the vault with %s", err.Error())
+		resp, err := u.sendKey(ctx, s, migrate)
+		switch {
+		case isBadRequest(err) && (status.T == 0 || len(keys)+1 < status.T):
+			klog.Warningf("skipping key = %s, vault rejected it with %s", keyID, err.Error())
+			report.Rejected = append(report.Rejected, keyID)
+			continue
+		case isBadRequest(err):
+			// the threshold is met, but vault failed to combine the keys
+			combine = true
+		case err != nil:
+			return err
+		case !resp.Sealed:
+			return nil
+		case resp.Progress == 0:
+			// if progress is 0, vault failed to combine the keys
+			combine = true
+		}
+		keys = append(keys, s)
+	}
+
+	if combine && status.T > 0 {
+		klog.Warningln("vault failed to combine the unseal keys, trying other combinations of them")
+		if unsealed, err := u.unsealWithCombinations(ctx, migrate, keys, status.T, report); unsealed || err != nil {
+			return err
+		}
+	}
+	return report
+}
+
+// unsealWithCombinations resets the unseal progress and sends combinations of
+// threshold keys until vault is unsealed. The first combination was tried
+// already, at most maxUnsealCombinations others are tried.
+func (u *unsealer) unsealWithCombinations(ctx context.Context, migrate bool, keys []share, threshold int, report *UnsealKeysError) (bool, error) {
+	for _, k := range keys {
+		report.Combined = append(report.Combined, k.id)
+	}
+	report.Combinations = 1
+	if len(keys) < threshold {
+		return false, nil
+	}
+
+	combination := make([]int, threshold)
+	for i := range combination {
+		combination[i] = i
+	}
+	for report.Combinations <= maxUnsealCombinations && nextCombination(combination, len(keys)) {
+		report.Combinations++
+
+		if _, err := u.cl.Sys().UnsealWithOptionsWithContext(ctx, &api.UnsealOpts{Reset: true}); err != nil {
+			return false, fmt.Errorf("failed to reset the unseal progress with %s", err.Error())
+		}
+		for _, i := range combination {
+			resp, err := u.sendKey(ctx, keys[i], migrate)
+			if isBadRequest(err) {
+				break
+			} else if err != nil {
+				return false, err
+			}
+			if !resp.Sealed {
+				return true, nil
+			}
+			if resp.Progress == 0 {
+				break
+			}
 		}
+	}
+	return false, nil
+}
 
-		klog.Infof("got an unseal response: %+v", *resp)
+// nextCombination advances combination to the next combination of its size
+// out of n elements in lexicographic order, it returns false after the last.
+func nextCombination(combination []int, n int) bool {
+	k := len(combination)
+	i := k - 1
+	for i >= 0 && combination[i] == n-k+i {
+		i--
+	}
+	if i < 0 {
+		return false
+	}
+	combination[i]++
+	for j := i + 1; j < k; j++ {
+		combination[j] = combination[j-1] + 1
+	}
+	return true
+}
 
-		if !resp.Sealed {
+// sendKey sends an unseal request with the key to vault
+func (u *unsealer) sendKey(ctx context.Context, s share, migrate bool) (*api.SealStatusResponse, error) {
+	klog.Infof("try to send unseal request to the vault with keyID = %s", s.id)
+	resp, err := u.cl.Sys().UnsealWithOptionsWithContext(ctx, &api.UnsealOpts{
+		Key:     s.key,
+		Migrate: migrate,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to send unseal request to the vault")
+	}
+
+	klog.Infof("got an unseal response: %+v", *resp)
+	return resp, nil
+}
+
+// isBadRequest tells whether vault rejected the request, e.g. because of an
+// invalid unseal key
+func isBadRequest(err error) bool {
+	var respErr *api.ResponseError
+	return errors.As(err, &respErr) && respErr.StatusCode == http.StatusBadRequest
+}
+
+// migrateSeal unseals a vault that migrates from the shamir seal to an
+// auto-unseal seal. The unseal keys become the recovery keys of vault, so
+// once the migration is done they are moved to the recovery keys.
+func (u *unsealer) migrateSeal(ctx context.Context, status *api.SealStatusResponse) error {
+	if !u.config.SealMigration {
+		return ErrSealMigrationDisabled
+	}
+
+	klog.Infof("vault is migrating its seal to %s, unsealing it with the migrate flag", status.Type)
+	if err := u.unsealWithKeys(ctx, true, status); err != nil {
+		return errors.Wrap(err, "failed to migrate the seal")
+	}
+	klog.Infoln("vault seal is migrated")
+
+	// vault is migrated, the keys have to be moved even if the unsealer stops
+	return u.moveToRecoveryKeys(context.WithoutCancel(ctx), status.N)
+}
+
+// FinishSealMigration moves the unseal keys of a vault that migrated to an
+// auto-unseal seal to the recovery keys, in case the unsealer stopped before
+// it moved them after the migration. It does nothing unless seal migration is
+// enabled and the migration is finished.
+func (u *unsealer) FinishSealMigration(ctx context.Context) error {
+	if !u.config.SealMigration {
+		return nil
+	}
+	status, err := u.sealStatus(ctx)
+	if err != nil {
+		return err
+	}
+	if status.Type == shamirSeal || status.Migration || status.Sealed {
+		return nil
+	}
+	return u.moveToRecoveryKeys(ctx, status.N)
+}
+
+// moveToRecoveryKeys copies the n unseal keys to the recovery keys, and
+// deletes the unseal keys once every key is copied. Missing keys are skipped,
+// existing recovery keys are only overwritten with OverwriteExisting unless
+// they hold the same key, so a move that was interrupted is finished by
+// calling it again.
+func (u *unsealer) moveToRecoveryKeys(ctx context.Context, n int) error {
+	if n == 0 {
+		n = u.config.SecretShares
+	}
+
+	keys := map[int][]byte{}
+	for i := 0; i < n; i++ {
+		keyID := util.UnsealKeyID(u.config.KeyPrefix, i)
+		k, err := u.keyStore.Get(ctx, keyID)
+		if _, ok := err.(*kv.NotFoundError); ok {
+			continue
+		} else if err != nil {
+			return errors.Wrapf(ErrKeyStoreUnreachable, "vault seal is migrated, but failed to get the unseal key = %s with %s", keyID, err.Error())
+		}
+		keys[i] = k
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	for i, k := range keys {
+		recoveryKeyID := util.RecoveryKeyID(u.config.KeyPrefix, i)
+		existing, err := u.keyStore.Get(ctx, recoveryKeyID)
+		if err == nil && bytes.Equal(existing, k) {
+			continue
+		} else if _, ok := err.(*kv.NotFoundError); err != nil && !ok {
+			return errors.Wrapf(ErrKeyStoreUnreachable, "vault seal is migrated, but failed to get the recovery key = %s with %s", recoveryKeyID, err.Error())
+		}
+		if err := u.keyStoreSet(ctx, recoveryKeyID, k); err != nil {
+			return errors.Wrapf(err, "vault seal is migrated, but failed to store the recovery key = %s, the unseal keys are kept", recoveryKeyID)
+		}
+	}
+	klog.Infof("%d unseal keys are stored as recovery keys", len(keys))
+
+	for i := range keys {
+		keyID := util.UnsealKeyID(u.config.KeyPrefix, i)
+		if err := u.keyStore.Delete(ctx, keyID); err != nil {
+			return errors.Wrapf(ErrKeyStoreUnreachable, "vault seal is migrated, but failed to delete the unseal key = %s with %s", keyID, err.Error())
+		}
+	}
+	klog.Infoln("unseal keys are deleted, they are kept as recovery keys")
+
+	return nil
+}
+
+func (u *unsealer) waitForAutoUnseal(ctx context.Context, sealType string) error {
+	klog.Infof("vault uses the %s seal, waiting for it to unseal itself", sealType)
+
+	if u.config.AutoUnsealTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, u.config.AutoUnsealTimeout)
+		defer cancel()
+	}
+	for {
+		sealed, err := u.IsSealed(ctx)
+		if err == nil && !sealed {
 			return nil
 		}
+		if u.config.AutoUnsealTimeout == 0 {
+			return errors.Wrapf(ErrAutoUnsealPending, "%s seal", sealType)
+		}
 
-		// if progress is 0, we failed to unseal vault.
-		if resp.Progress == 0 {
-			return fmt.Errorf("failed to unseal the vault, progress is reset to 0")
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(ErrAutoUnsealPending, "%s seal", sealType)
+		case <-time.After(autoUnsealPoll):
 		}
 	}
 }
 
-func (u *unsealer) keyStoreNotFound(key string) bool {
-	_, err := u.keyStore.Get(key)
+func (u *unsealer) keyStoreNotFound(ctx context.Context, key string) bool {
+	exists, err := u.keyExists(ctx, key)
 	if err != nil {
 		klog.Errorf("error while checking whether key (%s) exists or not with %v", key, err)
+		return false
 	}
+	return !exists
+}
+
+// keyExists returns an error if it could not be told whether key exists.
+func (u *unsealer) keyExists(ctx context.Context, key string) (bool, error) {
+	_, err := u.keyStore.Get(ctx, key)
 	if _, ok := err.(*kv.NotFoundError); ok {
-		return true
+		return false, nil
+	} else if err != nil {
+		return false, errors.Wrap(ErrKeyStoreUnreachable, err.Error())
 	}
-	return false
+	return true, nil
 }
 
-func (u *unsealer) keyStoreSet(key string, val []byte) error {
-	// We do not want to overwrite the existing keys, but key is already present.
-	if !u.config.OverwriteExisting && !u.keyStoreNotFound(key) {
-		return fmt.Errorf("error setting key %s to keystore, it already exists", key)
+// keyStoreSet stores the key, an existing key is only overwritten if that is
+// allowed. Otherwise the key is created, which fails if it exists. Init
+// stores the first share first, so of several unsealers that initialize at
+// the same time only the one that creates it can store its keys.
+func (u *unsealer) keyStoreSet(ctx context.Context, key string, val []byte) error {
+	if u.config.OverwriteExisting {
+		return u.keyStore.Set(ctx, key, val)
+	}
+
+	err := u.keyStore.Create(ctx, key, val)
+	if _, ok := err.(*kv.ExistsError); ok {
+		return errors.Wrapf(ErrKeyExists, "error setting key %s to keystore", key)
 	}
-	return u.keyStore.Set(key, val)
+	return err
 }
 
-func (u *unsealer) Init() error {
+// Init initializes vault and stores its keys. A vault with an auto-unseal
+// seal is initialized with recovery keys instead of unseal keys, SecretShares
+// and SecretThreshold are used for them.
+func (u *unsealer) Init(ctx context.Context) error {
 	// test the backend first
-	err := u.keyStore.Test(testKey(u.config.KeyPrefix))
+	err := u.keyStore.Test(ctx, testKey(u.config.KeyPrefix))
 	if err != nil {
-		return fmt.Errorf("error testing keystore before init with %s", err.Error())
+		return errors.Wrapf(ErrKeyStoreUnreachable, "error testing keystore before init with %s", err.Error())
+	}
+
+	status, err := u.sealStatus(ctx)
+	if err != nil {
+		return err
+	}
+	recovery := status.Type != shamirSeal
+	if recovery {
+		klog.Infof("vault uses the %s seal, it is initialized with recovery keys", status.Type)
 	}
 
 	// test for an existing key
@@ -139,37 +556,95 @@ func (u *unsealer) Init() error {
 
 		// add unseal keys
 		for i := 0; i <= u.config.SecretShares; i++ {
-			keys = append(keys, util.UnsealKeyID(u.config.KeyPrefix, i))
+			keys = append(keys, u.shareID(i, recovery, false))
+		}
+
+		// add custodian copies
+		if len(u.config.PGPKeys) > 0 || len(u.config.CustodianPGPKeys) > 0 {
+			for i := 0; i < u.config.SecretShares; i++ {
+				keys = append(keys, u.shareID(i, recovery, true))
+			}
+		}
+		if u.config.RootTokenPGPKey != "" {
+			keys = append(keys, util.CustodianRootTokenID(u.config.KeyPrefix))
 		}
 
 		// test every key
 		for _, key := range keys {
-			if !u.keyStoreNotFound(key) {
-				return fmt.Errorf("error before init: keystore value for '%s' already exists", key)
+			exists, err := u.keyExists(ctx, key)
+			if err != nil {
+				return errors.Wrapf(err, "error before init: failed to check keystore value for '%s'", key)
+			}
+			if exists {
+				return errors.Wrapf(ErrKeyExists, "error before init: keystore value for '%s'", key)
 			}
 		}
 	}
 
-	resp, err := u.cl.Sys().Init(&api.InitRequest{
-		SecretShares:    u.config.SecretShares,
-		SecretThreshold: u.config.SecretThreshold,
-	})
+	// From here on vault hands out the only copy of the keys. So once the
+	// request is made, neither the request nor storing the keys is allowed
+	// to be interrupted by cancellation; per call timeouts still apply.
+	if err := ctx.Err(); err != nil {
+		return errors.Wrap(err, "not initializing the vault")
+	}
+	ctx = context.WithoutCancel(ctx)
+
+	req := &api.InitRequest{
+		RootTokenPGPKey: u.config.RootTokenPGPKey,
+	}
+	if recovery {
+		req.RecoveryShares = u.config.SecretShares
+		req.RecoveryThreshold = u.config.SecretThreshold
+		req.RecoveryPGPKeys = u.config.PGPKeys
+	} else {
+		req.SecretShares = u.config.SecretShares
+		req.SecretThreshold = u.config.SecretThreshold
+		req.PGPKeys = u.config.PGPKeys
+	}
+
+	resp, err := u.cl.Sys().InitWithContext(ctx, req)
 	if err != nil {
 		return fmt.Errorf("failed to initialize the vault with %s", err.Error())
 	}
 
-	for i, k := range resp.Keys {
-		keyID := util.UnsealKeyID(u.config.KeyPrefix, i)
-		err := u.keyStoreSet(keyID, []byte(k))
+	shares := resp.Keys
+	if recovery {
+		shares = resp.RecoveryKeys
+	}
+
+	for i, k := range shares {
+		// vault returned the keys encrypted, only the key holders can use them
+		keyID := u.shareID(i, recovery, len(u.config.PGPKeys) > 0)
+		err := u.keyStoreSet(ctx, keyID, []byte(k))
 		if err != nil {
-			return fmt.Errorf("failed to store the unseal key = '%s' with %s", keyID, err.Error())
+			return errors.Wrapf(ErrKeysNotStored, "failed to store the unseal key = '%s' with %s", keyID, err.Error())
 		}
 	}
 
-	if u.config.StoreRootToken {
+	for i, key := range u.config.CustodianPGPKeys {
+		keyID := u.shareID(i, recovery, true)
+		encrypted, err := encryptShare(shares[i], key)
+		if err == nil {
+			err = u.keyStoreSet(ctx, keyID, []byte(encrypted))
+		}
+		if err != nil {
+			return errors.Wrapf(ErrKeysNotStored, "failed to store the custodian copy = '%s' with %s", keyID, err.Error())
+		}
+	}
+	if len(u.config.CustodianPGPKeys) > 0 {
+		klog.Infof("successfully stored %d pgp encrypted custodian copies of the unseal keys", len(u.config.CustodianPGPKeys))
+	}
+
+	if u.config.RootTokenPGPKey != "" {
+		rootTokenID := util.CustodianRootTokenID(u.config.KeyPrefix)
+		if err = u.keyStoreSet(ctx, rootTokenID, []byte(resp.RootToken)); err != nil {
+			return errors.Wrapf(ErrKeysNotStored, "failed to store the pgp encrypted root token with %v", err)
+		}
+		klog.Info("successfully stored the pgp encrypted root token")
+	} else if u.config.StoreRootToken {
 		rootTokenID := util.RootTokenID(u.config.KeyPrefix)
-		if err = u.keyStoreSet(rootTokenID, []byte(resp.RootToken)); err != nil {
-			return fmt.Errorf("failed to store the root token with %v", err)
+		if err = u.keyStoreSet(ctx, rootTokenID, []byte(resp.RootToken)); err != nil {
+			return errors.Wrapf(ErrKeysNotStored, "failed to store the root token with %v", err)
 		}
 		klog.Info("successfully stored the root token")
 	} else {
@@ -184,10 +659,10 @@ func testKey(prefix string) string {
 }
 
 // CheckReadWriteAccess will test read write access
-func (u *unsealer) CheckReadWriteAccess() error {
-	err := u.keyStore.CheckWriteAccess()
+func (u *unsealer) CheckReadWriteAccess(ctx context.Context) error {
+	err := u.keyStore.CheckWriteAccess(ctx)
 	if err != nil {
-		return errors.Wrap(err, "read/write access test failed")
+		return errors.Wrapf(ErrKeyStoreUnreachable, "read/write access test failed with %s", err.Error())
 	}
 
 	klog.Info("successfully tested the read/write access")
@@ -17,60 +17,545 @@ limitations under the License.
 package unseal
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
 	"testing"
+	"time"
 
-	"kubevault.dev/unsealer/pkg/kv"
+	"kubevault.dev/unsealer/pkg/internal/kvtest"
+	"kubevault.dev/unsealer/pkg/vault"
+
+	"github.com/appscode/pat"
+	"github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 )
 
-type fakeKV struct {
-	Values map[string]*[]byte
-}
+func TestKeyStoreNotFound(t *testing.T) {
+	store := &unreachableKV{
+		Store:       kvtest.New(map[string][]byte{"exists": []byte("data")}),
+		unreachable: []string{"error"},
+	}
+	v := &unsealer{
+		keyStore: store,
+	}
 
-func NewFakeKV() *fakeKV {
-	return &fakeKV{
-		Values: map[string]*[]byte{},
+	if !v.keyStoreNotFound(context.Background(), "not-found") {
+		t.Error("not returning true for notfound")
+	}
+
+	if v.keyStoreNotFound(context.Background(), "exists") {
+		t.Error("not returing false for existing")
+	}
+
+	if v.keyStoreNotFound(context.Background(), "error") {
+		t.Error("not returning false for error case")
 	}
 }
 
-func (f *fakeKV) Test(key string) error {
-	return fmt.Errorf("not-implemented")
+// fakeVault answers the seal status, init and unseal requests, the requests
+// are stored. It is unsealed once threshold unseal requests were made.
+type fakeVault struct {
+	sealType     string
+	keys         []string
+	recoveryKeys []string
+	rootToken    string
+	sealed       bool
+	migration    bool
+	threshold    int
+	shares       int
+
+	req        api.InitRequest
+	unsealReqs []api.UnsealOpts
+	// keys sent since the last reset
+	progress []string
+	// malformed keys are rejected at once, corrupt keys once vault fails to
+	// combine them with the others
+	malformed []string
+	corrupt   []string
+
+	// the rekey hands out newKeys once threshold keys were sent, and is
+	// verified with threshold of the new keys
+	newKeys       []string
+	rekeyReq      api.RekeyInitRequest
+	rekeyShares   []string
+	verifyShares  []string
+	rekeyCanceled bool
+
+	// the generate root hands out rootToken encoded with otp once threshold
+	// keys were sent
+	otp            string
+	rootShares     []string
+	rootCanceled   bool
+	rootInProgress bool
+
+	joinReq api.RaftJoinRequest
 }
 
-func (f *fakeKV) CheckWriteAccess() error {
-	return fmt.Errorf("not-implemented")
+func (f *fakeVault) client(t *testing.T) *api.Client {
+	m := pat.New()
+	m.Get("/v1/sys/seal-status", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		utilruntime.Must(json.NewEncoder(w).Encode(&api.SealStatusResponse{
+			Type:        f.sealType,
+			Initialized: true,
+			Sealed:      f.sealed,
+			Migration:   f.migration,
+			N:           f.shares,
+			T:           f.threshold,
+			Progress:    len(f.progress),
+		}))
+	}))
+	m.Put("/v1/sys/unseal", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close() //nolint:errcheck
+		var opts api.UnsealOpts
+		utilruntime.Must(json.NewDecoder(r.Body).Decode(&opts))
+		f.unsealReqs = append(f.unsealReqs, opts)
+		switch {
+		case opts.Reset:
+			f.progress = nil
+		case slices.Contains(f.malformed, opts.Key):
+			http.Error(w, `{"errors":["invalid key"]}`, http.StatusBadRequest)
+			return
+		default:
+			f.progress = append(f.progress, opts.Key)
+		}
+		if len(f.progress) >= f.threshold {
+			corrupt := slices.ContainsFunc(f.progress, func(k string) bool { return slices.Contains(f.corrupt, k) })
+			f.progress = nil
+			if corrupt {
+				http.Error(w, `{"errors":["failed to decrypt keyring"]}`, http.StatusBadRequest)
+				return
+			}
+			f.sealed, f.migration = false, false
+		}
+		utilruntime.Must(json.NewEncoder(w).Encode(&api.SealStatusResponse{
+			Type:     f.sealType,
+			Sealed:   f.sealed,
+			Progress: len(f.progress),
+		}))
+	}))
+	m.Put("/v1/sys/init", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close() //nolint:errcheck
+		utilruntime.Must(json.NewDecoder(r.Body).Decode(&f.req))
+		utilruntime.Must(json.NewEncoder(w).Encode(&api.InitResponse{
+			Keys:         f.keys,
+			RecoveryKeys: f.recoveryKeys,
+			RootToken:    f.rootToken,
+		}))
+	}))
+	m.Get("/v1/sys/rekey/init", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		utilruntime.Must(json.NewEncoder(w).Encode(&api.RekeyStatusResponse{}))
+	}))
+	m.Put("/v1/sys/rekey/init", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close() //nolint:errcheck
+		utilruntime.Must(json.NewDecoder(r.Body).Decode(&f.rekeyReq))
+		utilruntime.Must(json.NewEncoder(w).Encode(&api.RekeyStatusResponse{Nonce: "nonce", Started: true}))
+	}))
+	m.Del("/v1/sys/rekey/init", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.rekeyCanceled = true
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	m.Put("/v1/sys/rekey/update", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close() //nolint:errcheck
+		var req struct{ Key string }
+		utilruntime.Must(json.NewDecoder(r.Body).Decode(&req))
+		f.rekeyShares = append(f.rekeyShares, req.Key)
+		resp := &api.RekeyUpdateResponse{Nonce: "nonce"}
+		if len(f.rekeyShares) >= f.threshold {
+			resp.Complete = true
+			resp.Keys = f.newKeys
+			resp.VerificationRequired = true
+			resp.VerificationNonce = "verification-nonce"
+		}
+		utilruntime.Must(json.NewEncoder(w).Encode(resp))
+	}))
+	m.Put("/v1/sys/rekey/verify", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close() //nolint:errcheck
+		var req struct{ Key string }
+		utilruntime.Must(json.NewDecoder(r.Body).Decode(&req))
+		f.verifyShares = append(f.verifyShares, req.Key)
+		utilruntime.Must(json.NewEncoder(w).Encode(&api.RekeyVerificationUpdateResponse{
+			Nonce:    "verification-nonce",
+			Complete: len(f.verifyShares) >= f.rekeyReq.SecretThreshold,
+		}))
+	}))
+	m.Get("/v1/sys/generate-root/attempt", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		utilruntime.Must(json.NewEncoder(w).Encode(&api.GenerateRootStatusResponse{Started: f.rootInProgress}))
+	}))
+	m.Put("/v1/sys/generate-root/attempt", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.rootInProgress = true
+		utilruntime.Must(json.NewEncoder(w).Encode(&api.GenerateRootStatusResponse{Nonce: "nonce", Started: true, OTP: f.otp}))
+	}))
+	m.Del("/v1/sys/generate-root/attempt", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.rootCanceled, f.rootInProgress = true, false
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	m.Put("/v1/sys/generate-root/update", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close() //nolint:errcheck
+		var req struct{ Key string }
+		utilruntime.Must(json.NewDecoder(r.Body).Decode(&req))
+		f.rootShares = append(f.rootShares, req.Key)
+		resp := &api.GenerateRootStatusResponse{Nonce: "nonce", Started: true}
+		if len(f.rootShares) >= f.threshold {
+			encoded := make([]byte, len(f.rootToken))
+			for i := range encoded {
+				encoded[i] = f.rootToken[i] ^ f.otp[i]
+			}
+			resp.Complete = true
+			resp.EncodedToken = base64.RawStdEncoding.EncodeToString(encoded)
+			f.rootInProgress = false
+		}
+		utilruntime.Must(json.NewEncoder(w).Encode(resp))
+	}))
+	m.Post("/v1/sys/storage/raft/join", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close() //nolint:errcheck
+		utilruntime.Must(json.NewDecoder(r.Body).Decode(&f.joinReq))
+		utilruntime.Must(json.NewEncoder(w).Encode(&api.RaftJoinResponse{Joined: f.joinReq.LeaderAPIAddr != ""}))
+	}))
+	srv := httptest.NewServer(m)
+	t.Cleanup(srv.Close)
+
+	vc, err := vault.NewVaultClient(srv.URL, true, nil)
+	require.NoError(t, err)
+	return vc
 }
 
-func (f *fakeKV) Set(key string, data []byte) error {
-	return fmt.Errorf("not-implemented")
+func TestInit(t *testing.T) {
+	_, pgpKey := newTestPGPKey(t)
+
+	t.Run("plaintext keys", func(t *testing.T) {
+		fv := &fakeVault{sealType: "shamir", keys: []string{"k0", "k1"}, rootToken: "root"}
+		store := kvtest.New(nil)
+		u, err := New(store, fv.client(t), UnsealOptions{KeyPrefix: "vault", SecretShares: 2, SecretThreshold: 1, StoreRootToken: true})
+		require.NoError(t, err)
+
+		require.NoError(t, u.Init(context.Background()))
+		assert.Equal(t, 2, fv.req.SecretShares)
+		assert.Equal(t, map[string][]byte{
+			"vault-unseal-key-0": []byte("k0"),
+			"vault-unseal-key-1": []byte("k1"),
+			"vault-root-token":   []byte("root"),
+		}, store.Values())
+	})
+
+	t.Run("pgp keys", func(t *testing.T) {
+		fv := &fakeVault{sealType: "shamir", keys: []string{"e0", "e1"}, rootToken: "encrypted-root"}
+		store := kvtest.New(nil)
+		u, err := New(store, fv.client(t), UnsealOptions{
+			KeyPrefix:       "vault",
+			SecretShares:    2,
+			SecretThreshold: 1,
+			PGPKeys:         []string{pgpKey, pgpKey},
+			RootTokenPGPKey: pgpKey,
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, u.Init(context.Background()))
+		assert.Equal(t, []string{pgpKey, pgpKey}, fv.req.PGPKeys)
+		assert.Equal(t, pgpKey, fv.req.RootTokenPGPKey)
+		assert.Equal(t, map[string][]byte{
+			"vault-custodian-unseal-key-0": []byte("e0"),
+			"vault-custodian-unseal-key-1": []byte("e1"),
+			"vault-custodian-root-token":   []byte("encrypted-root"),
+		}, store.Values())
+
+		assert.ErrorIs(t, u.Unseal(context.Background()), ErrKeysEncrypted)
+	})
+
+	t.Run("custodian copies", func(t *testing.T) {
+		fv := &fakeVault{sealType: "shamir", keys: []string{"k0", "k1"}, rootToken: "root"}
+		store := kvtest.New(nil)
+		u, err := New(store, fv.client(t), UnsealOptions{
+			KeyPrefix:        "vault",
+			SecretShares:     2,
+			SecretThreshold:  1,
+			StoreRootToken:   true,
+			CustodianPGPKeys: []string{pgpKey, pgpKey},
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, u.Init(context.Background()))
+		assert.Empty(t, fv.req.PGPKeys)
+		assert.Equal(t, []byte("k0"), store.Values()["vault-unseal-key-0"])
+		assert.Contains(t, store.Values(), "vault-custodian-unseal-key-0")
+		assert.Contains(t, store.Values(), "vault-custodian-unseal-key-1")
+		assert.NotEqual(t, []byte("k0"), store.Values()["vault-custodian-unseal-key-0"])
+	})
+
+	t.Run("recovery keys", func(t *testing.T) {
+		fv := &fakeVault{sealType: "awskms", recoveryKeys: []string{"r0", "r1"}, rootToken: "root"}
+		store := kvtest.New(nil)
+		u, err := New(store, fv.client(t), UnsealOptions{KeyPrefix: "vault", SecretShares: 2, SecretThreshold: 1, StoreRootToken: true})
+		require.NoError(t, err)
+
+		require.NoError(t, u.Init(context.Background()))
+		assert.Equal(t, 0, fv.req.SecretShares)
+		assert.Equal(t, 2, fv.req.RecoveryShares)
+		assert.Equal(t, 1, fv.req.RecoveryThreshold)
+		assert.Equal(t, map[string][]byte{
+			"vault-recovery-key-0": []byte("r0"),
+			"vault-recovery-key-1": []byte("r1"),
+			"vault-root-token":     []byte("root"),
+		}, store.Values())
+
+		// vault unseals itself, the recovery keys are not used to unseal it
+		require.NoError(t, u.Unseal(context.Background()))
+	})
+
+	t.Run("keys of a concurrent init are kept", func(t *testing.T) {
+		fv := &fakeVault{sealType: "shamir", keys: []string{"k0", "k1"}, rootToken: "root"}
+		other := map[string][]byte{
+			"vault-unseal-key-0": []byte("o0"),
+			"vault-unseal-key-1": []byte("o1"),
+		}
+		store := &racingKV{Store: kvtest.New(nil), other: other}
+		u, err := New(store, fv.client(t), UnsealOptions{KeyPrefix: "vault", SecretShares: 2, SecretThreshold: 1, StoreRootToken: true})
+		require.NoError(t, err)
+
+		err = u.Init(context.Background())
+		assert.ErrorIs(t, err, ErrKeysNotStored)
+		assert.Equal(t, other, store.Values(), "no key of the losing init is stored")
+	})
 }
 
-func (f *fakeKV) Get(key string) ([]byte, error) {
-	switch key {
-	case "exists":
-		return []byte("data"), nil
-	case "not-found":
-		return nil, kv.NewNotFoundError("not-found")
+// racingKV stores the keys of another init right before the first key is
+// created, as if the other init passed the check for existing keys at the
+// same time
+type racingKV struct {
+	*kvtest.Store
+	other map[string][]byte
+}
+
+func (r *racingKV) Create(ctx context.Context, key string, data []byte) error {
+	for k, v := range r.other {
+		if err := r.Store.Set(ctx, k, v); err != nil {
+			return err
+		}
 	}
+	r.other = nil
+	return r.Store.Create(ctx, key, data)
+}
 
-	return nil, fmt.Errorf("not-implemented")
+func TestUnsealAutoUnseal(t *testing.T) {
+	fv := &fakeVault{sealType: "awskms", sealed: true, threshold: 2}
+	u, err := New(kvtest.New(nil), fv.client(t), UnsealOptions{KeyPrefix: "vault", AutoUnsealTimeout: 10 * time.Millisecond})
+	require.NoError(t, err)
+
+	start := time.Now()
+	assert.ErrorIs(t, u.Unseal(context.Background()), ErrAutoUnsealPending)
+	assert.Less(t, time.Since(start), autoUnsealPoll, "the timeout is taken from the options")
+	assert.Empty(t, fv.unsealReqs)
 }
 
-func TestKeyStoreNotFound(t *testing.T) {
-	fakeKV := NewFakeKV()
-	v := &unsealer{
-		keyStore: fakeKV,
+func TestUnsealSealMigration(t *testing.T) {
+	newStore := func() *kvtest.Store {
+		return kvtest.New(map[string][]byte{
+			"vault-unseal-key-0": []byte("k0"),
+			"vault-unseal-key-1": []byte("k1"),
+		})
 	}
 
-	if !v.keyStoreNotFound("not-found") {
-		t.Error("not returning true for notfound")
+	t.Run("migration is not enabled", func(t *testing.T) {
+		fv := &fakeVault{sealType: "awskms", sealed: true, migration: true, threshold: 2}
+		u, err := New(newStore(), fv.client(t), UnsealOptions{KeyPrefix: "vault", SecretShares: 2, SecretThreshold: 2})
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, u.Unseal(context.Background()), ErrSealMigrationDisabled)
+		assert.Empty(t, fv.unsealReqs)
+	})
+
+	t.Run("unseal keys become recovery keys", func(t *testing.T) {
+		fv := &fakeVault{sealType: "awskms", sealed: true, migration: true, threshold: 2}
+		store := newStore()
+		u, err := New(store, fv.client(t), UnsealOptions{KeyPrefix: "vault", SecretShares: 2, SecretThreshold: 2, SealMigration: true})
+		require.NoError(t, err)
+
+		require.NoError(t, u.Unseal(context.Background()))
+		assert.Equal(t, []api.UnsealOpts{{Key: "k0", Migrate: true}, {Key: "k1", Migrate: true}}, fv.unsealReqs)
+		assert.Equal(t, map[string][]byte{
+			"vault-recovery-key-0": []byte("k0"),
+			"vault-recovery-key-1": []byte("k1"),
+		}, store.Values())
+	})
+
+	t.Run("existing recovery keys are not overwritten", func(t *testing.T) {
+		fv := &fakeVault{sealType: "awskms", sealed: true, migration: true, threshold: 2}
+		store := newStore()
+		require.NoError(t, store.Set(context.Background(), "vault-recovery-key-1", []byte("r1")))
+		u, err := New(store, fv.client(t), UnsealOptions{KeyPrefix: "vault", SecretShares: 2, SecretThreshold: 2, SealMigration: true})
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, u.Unseal(context.Background()), ErrKeyExists)
+		values := store.Values()
+		assert.Equal(t, []byte("r1"), values["vault-recovery-key-1"])
+		assert.Equal(t, []byte("k0"), values["vault-unseal-key-0"], "unseal keys are kept")
+		assert.Equal(t, []byte("k1"), values["vault-unseal-key-1"])
+	})
+
+	t.Run("every share vault reports is moved, missing ones are skipped", func(t *testing.T) {
+		fv := &fakeVault{sealType: "awskms", sealed: true, migration: true, threshold: 2, shares: 3}
+		store := kvtest.New(map[string][]byte{
+			"vault-unseal-key-0": []byte("k0"),
+			"vault-unseal-key-2": []byte("k2"),
+		})
+		u, err := New(store, fv.client(t), UnsealOptions{KeyPrefix: "vault", SecretShares: 2, SecretThreshold: 2, SealMigration: true})
+		require.NoError(t, err)
+
+		require.NoError(t, u.Unseal(context.Background()))
+		assert.Equal(t, map[string][]byte{
+			"vault-recovery-key-0": []byte("k0"),
+			"vault-recovery-key-2": []byte("k2"),
+		}, store.Values())
+	})
+}
+
+func TestFinishSealMigration(t *testing.T) {
+	newStore := func() *kvtest.Store {
+		return kvtest.New(map[string][]byte{
+			"vault-unseal-key-0":   []byte("k0"),
+			"vault-unseal-key-1":   []byte("k1"),
+			"vault-recovery-key-0": []byte("k0"),
+		})
 	}
 
-	if v.keyStoreNotFound("exists") {
-		t.Error("not returing false for existing")
+	t.Run("an interrupted move is finished", func(t *testing.T) {
+		fv := &fakeVault{sealType: "awskms", threshold: 2, shares: 2}
+		store := newStore()
+		u, err := New(store, fv.client(t), UnsealOptions{KeyPrefix: "vault", SealMigration: true})
+		require.NoError(t, err)
+
+		require.NoError(t, u.FinishSealMigration(context.Background()))
+		assert.Equal(t, map[string][]byte{
+			"vault-recovery-key-0": []byte("k0"),
+			"vault-recovery-key-1": []byte("k1"),
+		}, store.Values())
+	})
+
+	t.Run("nothing is moved while vault still migrates", func(t *testing.T) {
+		fv := &fakeVault{sealType: "awskms", sealed: true, migration: true, threshold: 2, shares: 2}
+		store := newStore()
+		u, err := New(store, fv.client(t), UnsealOptions{KeyPrefix: "vault", SealMigration: true})
+		require.NoError(t, err)
+
+		require.NoError(t, u.FinishSealMigration(context.Background()))
+		assert.Equal(t, newStore().Values(), store.Values())
+	})
+
+	t.Run("nothing is moved if seal migration is not enabled", func(t *testing.T) {
+		fv := &fakeVault{sealType: "awskms", threshold: 2, shares: 2}
+		store := newStore()
+		u, err := New(store, fv.client(t), UnsealOptions{KeyPrefix: "vault"})
+		require.NoError(t, err)
+
+		require.NoError(t, u.FinishSealMigration(context.Background()))
+		assert.Equal(t, newStore().Values(), store.Values())
+	})
+}
+
+// unreachableKV fails to read the given keys as if their store was down
+type unreachableKV struct {
+	*kvtest.Store
+	unreachable []string
+}
+
+func (u *unreachableKV) Get(ctx context.Context, key string) ([]byte, error) {
+	for _, k := range u.unreachable {
+		if k == key {
+			return nil, fmt.Errorf("dial tcp: connection refused")
+		}
 	}
+	return u.Store.Get(ctx, key)
+}
 
-	if v.keyStoreNotFound("error") {
-		t.Error("not returning false for error case")
+func TestUnsealUnreachableKeys(t *testing.T) {
+	newStore := func(unreachable ...string) *unreachableKV {
+		store := kvtest.New(map[string][]byte{
+			"vault-unseal-key-0": []byte("k0"),
+			"vault-unseal-key-1": []byte("k1"),
+			"vault-unseal-key-2": []byte("k2"),
+		})
+		return &unreachableKV{Store: store, unreachable: unreachable}
+	}
+
+	t.Run("unreachable keys are skipped", func(t *testing.T) {
+		fv := &fakeVault{sealType: "shamir", sealed: true, threshold: 2, shares: 3}
+		u, err := New(newStore("vault-unseal-key-0"), fv.client(t), UnsealOptions{KeyPrefix: "vault"})
+		require.NoError(t, err)
+
+		require.NoError(t, u.Unseal(context.Background()))
+		assert.Equal(t, []api.UnsealOpts{{Key: "k1"}, {Key: "k2"}}, fv.unsealReqs)
+	})
+
+	t.Run("unreachable keys are reported if too few keys are readable", func(t *testing.T) {
+		fv := &fakeVault{sealType: "shamir", sealed: true, threshold: 2, shares: 3}
+		u, err := New(newStore("vault-unseal-key-0", "vault-unseal-key-2"), fv.client(t), UnsealOptions{KeyPrefix: "vault"})
+		require.NoError(t, err)
+
+		err = u.Unseal(context.Background())
+		assert.ErrorIs(t, err, ErrKeyStoreUnreachable)
+		assert.ErrorContains(t, err, "vault-unseal-key-0, vault-unseal-key-2")
+		assert.Equal(t, []api.UnsealOpts{{Key: "k1"}}, fv.unsealReqs)
+	})
+}
+
+func TestUnsealBadKeys(t *testing.T) {
+	newStore := func() *kvtest.Store {
+		return kvtest.New(map[string][]byte{
+			"vault-unseal-key-0": []byte("k0"),
+			"vault-unseal-key-1": []byte("k1"),
+			"vault-unseal-key-3": []byte("k3"),
+			"vault-unseal-key-4": []byte("k4"),
+		})
 	}
+
+	t.Run("missing and rejected keys are skipped", func(t *testing.T) {
+		fv := &fakeVault{sealType: "shamir", sealed: true, threshold: 2, shares: 5, malformed: []string{"k0"}}
+		u, err := New(newStore(), fv.client(t), UnsealOptions{KeyPrefix: "vault"})
+		require.NoError(t, err)
+
+		require.NoError(t, u.Unseal(context.Background()))
+		assert.Equal(t, []api.UnsealOpts{{Key: "k0"}, {Key: "k1"}, {Key: "k3"}}, fv.unsealReqs)
+	})
+
+	t.Run("keys sent before are discarded", func(t *testing.T) {
+		fv := &fakeVault{sealType: "shamir", sealed: true, threshold: 2, shares: 5, progress: []string{"stale"}, corrupt: []string{"stale"}}
+		u, err := New(newStore(), fv.client(t), UnsealOptions{KeyPrefix: "vault"})
+		require.NoError(t, err)
+
+		require.NoError(t, u.Unseal(context.Background()))
+		assert.Equal(t, []api.UnsealOpts{{Reset: true}, {Key: "k0"}, {Key: "k1"}}, fv.unsealReqs)
+	})
+
+	t.Run("other combinations are tried if vault fails to combine the keys", func(t *testing.T) {
+		fv := &fakeVault{sealType: "shamir", sealed: true, threshold: 2, shares: 5, corrupt: []string{"k1"}}
+		u, err := New(newStore(), fv.client(t), UnsealOptions{KeyPrefix: "vault"})
+		require.NoError(t, err)
+
+		require.NoError(t, u.Unseal(context.Background()))
+		assert.Equal(t, []api.UnsealOpts{
+			{Key: "k0"}, {Key: "k1"},
+			{Reset: true}, {Key: "k0"}, {Key: "k3"},
+		}, fv.unsealReqs)
+	})
+
+	t.Run("every unusable key is reported", func(t *testing.T) {
+		fv := &fakeVault{sealType: "shamir", sealed: true, threshold: 3, shares: 5, malformed: []string{"k0"}, corrupt: []string{"k3"}}
+		u, err := New(newStore(), fv.client(t), UnsealOptions{KeyPrefix: "vault"})
+		require.NoError(t, err)
+
+		err = u.Unseal(context.Background())
+		var keysErr *UnsealKeysError
+		require.ErrorAs(t, err, &keysErr)
+		assert.NotErrorIs(t, err, ErrKeyStoreUnreachable)
+		assert.Equal(t, []string{"vault-unseal-key-2"}, keysErr.Missing)
+		assert.Equal(t, []string{"vault-unseal-key-0"}, keysErr.Rejected)
+		assert.Equal(t, []string{"vault-unseal-key-1", "vault-unseal-key-3", "vault-unseal-key-4"}, keysErr.Combined)
+		assert.Equal(t, 1, keysErr.Combinations)
+	})
 }
@@ -16,7 +16,10 @@ limitations under the License.
 
 package util
 
-import "fmt"
+import (
+	"fmt"
+	"regexp"
+)
 
 // UnsealKeyID is the ID that used as key name when storing unseal key
 func UnsealKeyID(prefix string, i int) string {
@@ -27,3 +30,68 @@ func UnsealKeyID(prefix string, i int) string {
 func RootTokenID(prefix string) string {
 	return fmt.Sprintf("%s-root-token", prefix)
 }
+
+// CustodianUnsealKeyID is the ID that used as key name when storing a PGP
+// encrypted copy of an unseal key
+func CustodianUnsealKeyID(prefix string, i int) string {
+	return fmt.Sprintf("%s-custodian-unseal-key-%d", prefix, i)
+}
+
+// CustodianRootTokenID is the ID that used as key name when storing the PGP
+// encrypted root token
+func CustodianRootTokenID(prefix string) string {
+	return fmt.Sprintf("%s-custodian-root-token", prefix)
+}
+
+// RecoveryKeyID is the ID that used as key name when storing recovery key
+// of a vault that is unsealed by an auto-unseal seal
+func RecoveryKeyID(prefix string, i int) string {
+	return fmt.Sprintf("%s-recovery-key-%d", prefix, i)
+}
+
+// CustodianRecoveryKeyID is the ID that used as key name when storing a PGP
+// encrypted copy of a recovery key
+func CustodianRecoveryKeyID(prefix string, i int) string {
+	return fmt.Sprintf("%s-custodian-recovery-key-%d", prefix, i)
+}
+
+// StagedUnsealKeyID is the ID that used as key name when staging a new
+// unseal key during a rekey
+func StagedUnsealKeyID(prefix string, i int) string {
+	return fmt.Sprintf("%s-staged-unseal-key-%d", prefix, i)
+}
+
+// ArchivedRootTokenID is the ID that used as key name when keeping the root
+// token until it is revoked, once a scoped admin token is stored instead
+func ArchivedRootTokenID(prefix string) string {
+	return fmt.Sprintf("%s-archived-root-token", prefix)
+}
+
+// SnapshotID is the ID that used as key name when storing the manifest of the
+// raft snapshot in the given slot
+func SnapshotID(prefix string, slot int) string {
+	return fmt.Sprintf("%s-snapshot-%d", prefix, slot)
+}
+
+// SnapshotChunkID is the ID that used as key name when storing a part of the
+// raft snapshot in the given slot
+func SnapshotChunkID(prefix string, slot, i int) string {
+	return fmt.Sprintf("%s-snapshot-%d-chunk-%d", prefix, slot, i)
+}
+
+// keyID matches the IDs above, the prefix is as short as possible so that a
+// custodian, staged or archived key is not taken for a key of a longer prefix
+var keyID = regexp.MustCompile(`^(.+?)-((?:custodian-|staged-)?(?:unseal|recovery)-key-\d+|(?:custodian-|archived-)?root-token|snapshot-\d+(?:-chunk-\d+)?)$`)
+
+var index = regexp.MustCompile(`-\d+`)
+
+// ParseKeyID returns the prefix and the kind of a key ID, like "unseal-key",
+// "root-token" or "snapshot-chunk". It returns false if the ID does not follow
+// the naming scheme of the IDs above.
+func ParseKeyID(id string) (prefix, kind string, ok bool) {
+	m := keyID.FindStringSubmatch(id)
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], index.ReplaceAllString(m[2], ""), true
+}
@@ -0,0 +1,56 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseKeyID(t *testing.T) {
+	testData := []struct {
+		id     string
+		prefix string
+		kind   string
+		ok     bool
+	}{
+		{UnsealKeyID("vault", 2), "vault", "unseal-key", true},
+		{RecoveryKeyID("vault", 0), "vault", "recovery-key", true},
+		{RootTokenID("vault"), "vault", "root-token", true},
+		{CustodianUnsealKeyID("vault", 1), "vault", "custodian-unseal-key", true},
+		{CustodianRecoveryKeyID("vault", 1), "vault", "custodian-recovery-key", true},
+		{CustodianRootTokenID("vault"), "vault", "custodian-root-token", true},
+		{StagedUnsealKeyID("vault", 3), "vault", "staged-unseal-key", true},
+		{ArchivedRootTokenID("vault"), "vault", "archived-root-token", true},
+		{SnapshotID("vault", 4), "vault", "snapshot", true},
+		{SnapshotChunkID("vault", 4, 12), "vault", "snapshot-chunk", true},
+		{UnsealKeyID("old-vault", 0), "old-vault", "unseal-key", true},
+		{"vault-unsealer-dummy-file", "", "", false},
+		{"unseal-key-0", "", "", false},
+		{"vault-unseal-key-x", "", "", false},
+	}
+
+	for _, test := range testData {
+		t.Run(test.id, func(t *testing.T) {
+			prefix, kind, ok := ParseKeyID(test.id)
+			assert.Equal(t, test.ok, ok)
+			assert.Equal(t, test.prefix, prefix)
+			assert.Equal(t, test.kind, kind)
+		})
+	}
+}
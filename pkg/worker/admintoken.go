@@ -0,0 +1,183 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package worker
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"time"
+
+	"kubevault.dev/unsealer/pkg/kv"
+	"kubevault.dev/unsealer/pkg/vault/policy"
+
+	vaultapi "github.com/hashicorp/vault/api"
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+)
+
+// scopeAdminToken replaces the root token in the key store by an orphan
+// periodic token that is only allowed to configure vault, and revokes the
+// root token. The root token is archived until it is revoked, a failed revoke
+// is retried by the next call. The vault client has to use the stored token.
+// A token that is scoped already is kept, it may not write its own policy.
+func (w *worker) scopeAdminToken(ctx context.Context, vc *vaultapi.Client) error {
+	self, err := vc.Auth().Token().LookupSelfWithContext(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to look up the stored token")
+	}
+	policies, err := self.TokenPolicies()
+	if err != nil {
+		return errors.Wrap(err, "failed to read the policies of the stored token")
+	}
+	if !slices.Contains(policies, "root") {
+		return w.revokeArchivedRootToken(ctx, vc)
+	}
+
+	klog.Infoln("replacing the stored root token by a scoped admin token")
+
+	if err := policy.EnsureUnsealerPolicy(ctx, vc, w.PolicyManagerOptions.Name); err != nil {
+		return errors.Wrap(err, "failed to write the policy of the scoped admin token")
+	}
+
+	secret, err := vc.Auth().Token().CreateOrphanWithContext(ctx, &vaultapi.TokenCreateRequest{
+		Policies:    []string{policy.UnsealerPolicyName},
+		Period:      w.AdminTokenPeriod.String(),
+		DisplayName: "vault-unsealer",
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to create the scoped admin token")
+	}
+	token, err := secret.TokenID()
+	if err != nil {
+		return errors.Wrap(err, "failed to read the scoped admin token")
+	}
+
+	// the root token stays in the key store until it is revoked
+	if err := w.keyStore.Set(ctx, w.archivedRootTokenID, []byte(vc.Token())); err != nil {
+		w.revokeScopedToken(ctx, vc, token)
+		return errors.Wrap(err, "failed to archive the root token")
+	}
+	if err := w.keyStore.Set(ctx, w.rootTokenID, []byte(token)); err != nil {
+		// the root token is still stored, the new token is of no use
+		w.revokeScopedToken(ctx, vc, token)
+		return errors.Wrap(err, "failed to store the scoped admin token")
+	}
+	klog.Infoln("scoped admin token is stored")
+
+	rootToken := vc.Token()
+	vc.SetToken(token)
+	w.adminTokenRenewAt = time.Now().Add(w.AdminTokenPeriod / 2)
+	return w.revokeRootToken(ctx, vc, rootToken)
+}
+
+// revokeScopedToken revokes a scoped admin token that is not stored, the
+// client has to use the root token
+func (w *worker) revokeScopedToken(ctx context.Context, vc *vaultapi.Client, token string) {
+	if err := vc.Auth().Token().RevokeOrphanWithContext(context.WithoutCancel(ctx), token); err != nil {
+		klog.Errorf("failed to revoke the scoped admin token with %s", err.Error())
+	}
+}
+
+// revokeArchivedRootToken revokes the root token that is archived, if any
+func (w *worker) revokeArchivedRootToken(ctx context.Context, vc *vaultapi.Client) error {
+	rootToken, err := w.keyStore.Get(ctx, w.archivedRootTokenID)
+	if _, ok := err.(*kv.NotFoundError); ok {
+		return nil
+	} else if err != nil {
+		return errors.Wrap(err, "failed to get the archived root token")
+	}
+	klog.Infoln("retrying to revoke the archived root token")
+	return w.revokeRootToken(ctx, vc, string(rootToken))
+}
+
+// revokeRootToken revokes the root token and removes it from the archive. A
+// root token that vault does not know of any more is revoked already. The
+// revoke is not cancelled, the token must not outlive its use.
+func (w *worker) revokeRootToken(ctx context.Context, vc *vaultapi.Client, rootToken string) error {
+	ctx = context.WithoutCancel(ctx)
+
+	rc, err := vc.Clone()
+	if err != nil {
+		return errors.Wrap(err, "failed to create vault api client")
+	}
+	rc.SetToken(rootToken)
+
+	var respErr *vaultapi.ResponseError
+	err = rc.Auth().Token().RevokeSelfWithContext(ctx, "")
+	if errors.As(err, &respErr) && respErr.StatusCode == http.StatusForbidden {
+		klog.Infoln("archived root token is no longer valid")
+	} else if err != nil {
+		return errors.Wrapf(err, "failed to revoke the root token, it is kept as %s until it is revoked", w.archivedRootTokenID)
+	}
+
+	if err := w.keyStore.Delete(ctx, w.archivedRootTokenID); err != nil {
+		return errors.Wrap(err, "failed to delete the archived root token")
+	}
+	klog.Infoln("root token is revoked")
+	return nil
+}
+
+// renewAdminToken renews the stored admin token once half of its period is
+// left. Tokens that are not renewable, like the root token, are left alone.
+// The client of the server is shared, it does not get the token.
+func (w *worker) renewAdminToken(ctx context.Context, n *node) error {
+	if time.Now().Before(w.adminTokenRenewAt) {
+		return nil
+	}
+
+	token, err := w.keyStore.Get(ctx, w.rootTokenID)
+	if _, ok := err.(*kv.NotFoundError); ok {
+		return nil
+	} else if err != nil {
+		return errors.Wrap(err, "failed to get the admin token")
+	}
+
+	vc, err := n.vc.Clone()
+	if err != nil {
+		return errors.Wrap(err, "failed to create vault api client")
+	}
+	vc.SetToken(string(token))
+
+	self, err := vc.Auth().Token().LookupSelfWithContext(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to look up the admin token")
+	}
+	renewable, err := self.TokenIsRenewable()
+	if err != nil {
+		return errors.Wrap(err, "failed to read the admin token")
+	}
+	ttl, err := self.TokenTTL()
+	if err != nil {
+		return errors.Wrap(err, "failed to read the admin token")
+	}
+
+	half := w.AdminTokenPeriod / 2
+	switch {
+	case !renewable:
+		w.adminTokenRenewAt = time.Now().Add(half)
+	case ttl > half:
+		w.adminTokenRenewAt = time.Now().Add(ttl - half)
+	default:
+		if _, err := vc.Auth().Token().RenewSelfWithContext(ctx, 0); err != nil {
+			return errors.Wrap(err, "failed to renew the admin token")
+		}
+		w.adminTokenRenewAt = time.Now().Add(half)
+		klog.Infoln("admin token is renewed")
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"kubevault.dev/unsealer/pkg/internal/kvtest"
+	"kubevault.dev/unsealer/pkg/vault"
+
+	"github.com/appscode/pat"
+	vaultapi "github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+)
+
+// tokenVault answers the token requests made to scope and renew the admin
+// token, tokens maps every valid token to its policies.
+type tokenVault struct {
+	tokens   map[string][]string
+	ttl      int
+	policy   string
+	renewals int
+	// revoking a token fails
+	revokeFails bool
+}
+
+func (f *tokenVault) client(t *testing.T) *vaultapi.Client {
+	secret := func(w http.ResponseWriter, data map[string]any) {
+		utilruntime.Must(json.NewEncoder(w).Encode(&vaultapi.Secret{Data: data}))
+	}
+
+	m := pat.New()
+	m.Get("/v1/auth/token/lookup-self", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		policies, ok := f.tokens[r.Header.Get("X-Vault-Token")]
+		if !ok {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		root := len(policies) == 1 && policies[0] == "root"
+		ttl := f.ttl
+		if root {
+			ttl = 0
+		}
+		secret(w, map[string]any{"policies": policies, "renewable": !root, "ttl": ttl})
+	}))
+	m.Put("/v1/sys/policies/acl/vault-unsealer", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close() //nolint:errcheck
+		var req map[string]string
+		utilruntime.Must(json.NewDecoder(r.Body).Decode(&req))
+		f.policy = req["policy"]
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	m.Post("/v1/auth/token/create-orphan", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close() //nolint:errcheck
+		var req vaultapi.TokenCreateRequest
+		utilruntime.Must(json.NewDecoder(r.Body).Decode(&req))
+		f.tokens["scoped"] = req.Policies
+		utilruntime.Must(json.NewEncoder(w).Encode(&vaultapi.Secret{Auth: &vaultapi.SecretAuth{ClientToken: "scoped", Policies: req.Policies}}))
+	}))
+	m.Put("/v1/auth/token/revoke-self", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if f.revokeFails {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if _, ok := f.tokens[r.Header.Get("X-Vault-Token")]; !ok {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		delete(f.tokens, r.Header.Get("X-Vault-Token"))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	m.Put("/v1/auth/token/renew-self", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.renewals++
+		utilruntime.Must(json.NewEncoder(w).Encode(&vaultapi.Secret{Auth: &vaultapi.SecretAuth{ClientToken: "scoped", Renewable: true}}))
+	}))
+	srv := httptest.NewServer(m)
+	t.Cleanup(srv.Close)
+
+	vc, err := vault.NewVaultClient(srv.URL, true, nil)
+	require.NoError(t, err)
+	return vc
+}
+
+func TestAdminToken(t *testing.T) {
+	newWorker := func() (*worker, *kvtest.Store) {
+		store := kvtest.New(map[string][]byte{"vault-root": []byte("root")})
+		w := newTestWorker(nil)
+		w.keyStore = store
+		w.rootTokenID = "vault-root"
+		w.archivedRootTokenID = "vault-archived-root"
+		w.PolicyManagerOptions.Name = "vault-policy-controller"
+		return w, store
+	}
+
+	t.Run("root token is replaced by a scoped token", func(t *testing.T) {
+		fv := &tokenVault{tokens: map[string][]string{"root": {"root"}}}
+		vc := fv.client(t)
+		w, store := newWorker()
+
+		vc.SetToken("root")
+		require.NoError(t, w.scopeAdminToken(context.Background(), vc))
+		assert.Contains(t, fv.policy, `path "sys/policies/acl/vault-policy-controller"`)
+		assert.Contains(t, fv.policy, `path "auth/kubernetes/role/vault-policy-controller"`)
+		assert.NotContains(t, fv.policy, "*", "no other policy or role may be written")
+		assert.Equal(t, []byte("scoped"), store.Values()["vault-root"])
+		assert.Equal(t, map[string][]string{"scoped": {"vault-unsealer"}}, fv.tokens, "root token is revoked")
+		assert.Equal(t, "scoped", vc.Token())
+		assert.NotContains(t, store.Values(), "vault-archived-root", "revoked root token is not kept")
+
+		fv.policy = ""
+		require.NoError(t, w.scopeAdminToken(context.Background(), vc), "scoped token is kept")
+		assert.Equal(t, []byte("scoped"), store.Values()["vault-root"])
+		assert.Empty(t, fv.policy, "scoped token does not write its own policy")
+	})
+
+	t.Run("root token is kept until it is revoked", func(t *testing.T) {
+		fv := &tokenVault{tokens: map[string][]string{"root": {"root"}}, revokeFails: true}
+		vc := fv.client(t)
+		w, store := newWorker()
+
+		vc.SetToken("root")
+		assert.ErrorContains(t, w.scopeAdminToken(context.Background(), vc), "it is kept as vault-archived-root")
+		assert.Equal(t, []byte("scoped"), store.Values()["vault-root"])
+		assert.Equal(t, []byte("root"), store.Values()["vault-archived-root"])
+		assert.Equal(t, "scoped", vc.Token())
+		assert.Contains(t, fv.tokens, "root")
+
+		fv.revokeFails = false
+		require.NoError(t, w.scopeAdminToken(context.Background(), vc), "revoke is retried")
+		assert.Equal(t, map[string][]string{"scoped": {"vault-unsealer"}}, fv.tokens, "root token is revoked")
+		assert.NotContains(t, store.Values(), "vault-archived-root")
+	})
+
+	t.Run("archived root token that is revoked already is removed", func(t *testing.T) {
+		fv := &tokenVault{tokens: map[string][]string{"scoped": {"vault-unsealer"}}}
+		vc := fv.client(t)
+		w, store := newWorker()
+		require.NoError(t, store.Set(context.Background(), "vault-root", []byte("scoped")))
+		require.NoError(t, store.Set(context.Background(), "vault-archived-root", []byte("root")))
+
+		vc.SetToken("scoped")
+		require.NoError(t, w.scopeAdminToken(context.Background(), vc))
+		assert.NotContains(t, store.Values(), "vault-archived-root")
+	})
+
+	t.Run("scoped token is renewed once half of its period is left", func(t *testing.T) {
+		period := int(AdminTokenPeriodDefault / time.Second)
+		fv := &tokenVault{tokens: map[string][]string{"scoped": {"vault-unsealer"}}, ttl: period}
+		vc := fv.client(t)
+		w, store := newWorker()
+		require.NoError(t, store.Set(context.Background(), "vault-root", []byte("scoped")))
+
+		require.NoError(t, w.renewAdminToken(context.Background(), &node{vc: vc}))
+		assert.Equal(t, 0, fv.renewals)
+		assert.WithinDuration(t, time.Now().Add(AdminTokenPeriodDefault/2), w.adminTokenRenewAt, time.Minute)
+
+		fv.ttl = period / 4
+		require.NoError(t, w.renewAdminToken(context.Background(), &node{vc: vc}))
+		assert.Equal(t, 0, fv.renewals, "not looked up again before the renewal time")
+
+		w.adminTokenRenewAt = time.Time{}
+		require.NoError(t, w.renewAdminToken(context.Background(), &node{vc: vc}))
+		assert.Equal(t, 1, fv.renewals)
+		assert.Empty(t, vc.Token(), "the shared client does not get the admin token")
+	})
+
+	t.Run("root token is not renewed", func(t *testing.T) {
+		fv := &tokenVault{tokens: map[string][]string{"root": {"root"}}}
+		w, _ := newWorker()
+
+		require.NoError(t, w.renewAdminToken(context.Background(), &node{vc: fv.client(t)}))
+		assert.Equal(t, 0, fv.renewals)
+	})
+}
@@ -0,0 +1,101 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package worker
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// health tracks the outcome of the reconcile passes, it backs the liveness
+// and readiness endpoints.
+type health struct {
+	mu sync.RWMutex
+
+	// the liveness check fails if no pass completed within this window
+	window time.Duration
+
+	// completion time of the last pass, or the start time before the first one
+	lastPass time.Time
+	// error of the last pass
+	lastErr error
+	// at least one pass completed
+	passed bool
+	// state of every vault server after the last pass
+	nodes map[string]string
+}
+
+func newHealth(window time.Duration) *health {
+	return &health{
+		window:   window,
+		lastPass: time.Now(),
+	}
+}
+
+// observe records the result of a completed reconcile pass, nodes holds the
+// state of every vault server.
+func (h *health) observe(err error, nodes map[string]string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastPass = time.Now()
+	h.lastErr = err
+	h.passed = true
+	h.nodes = nodes
+}
+
+// healthz fails if the worker loop looks stuck.
+func (h *health) healthz(w http.ResponseWriter, _ *http.Request) {
+	h.mu.RLock()
+	since := time.Since(h.lastPass)
+	h.mu.RUnlock()
+
+	if since > h.window {
+		http.Error(w, fmt.Sprintf("no reconcile pass completed in the last %s", since.Round(time.Second)), http.StatusServiceUnavailable)
+		return
+	}
+	_, _ = fmt.Fprintln(w, "ok")
+}
+
+// readyz succeeds only if the last reconcile pass succeeded, the state of
+// every vault server is listed below the result.
+func (h *health) readyz(w http.ResponseWriter, _ *http.Request) {
+	h.mu.RLock()
+	passed, lastErr, nodes := h.passed, h.lastErr, h.nodes
+	h.mu.RUnlock()
+
+	switch {
+	case !passed:
+		http.Error(w, "no reconcile pass completed yet", http.StatusServiceUnavailable)
+	case lastErr != nil:
+		http.Error(w, fmt.Sprintf("last reconcile pass failed: %s", lastErr.Error()), http.StatusServiceUnavailable)
+	default:
+		_, _ = fmt.Fprintln(w, "ok")
+	}
+
+	addresses := make([]string, 0, len(nodes))
+	for address := range nodes {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	for _, address := range addresses {
+		_, _ = fmt.Fprintf(w, "%s: %s\n", address, nodes[address])
+	}
+}
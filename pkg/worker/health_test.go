@@ -0,0 +1,54 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package worker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealth(t *testing.T) {
+	check := func(handler http.HandlerFunc) int {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rec.Code
+	}
+
+	h := newHealth(time.Minute)
+	assert.Equal(t, http.StatusOK, check(h.healthz))
+	assert.Equal(t, http.StatusServiceUnavailable, check(h.readyz), "not ready before the first pass")
+
+	h.observe(errors.New("vault is sealed"), map[string]string{"https://vault-0:8200": "sealed"})
+	assert.Equal(t, http.StatusOK, check(h.healthz))
+	assert.Equal(t, http.StatusServiceUnavailable, check(h.readyz), "not ready after a failed pass")
+
+	h.observe(nil, map[string]string{"https://vault-0:8200": "unsealed"})
+	assert.Equal(t, http.StatusOK, check(h.healthz))
+	assert.Equal(t, http.StatusOK, check(h.readyz))
+
+	rec := httptest.NewRecorder()
+	h.readyz(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "ok\nhttps://vault-0:8200: unsealed\n", rec.Body.String(), "lists the state of every vault server")
+
+	h.lastPass = time.Now().Add(-2 * time.Minute)
+	assert.Equal(t, http.StatusServiceUnavailable, check(h.healthz), "not alive if no pass completed within the window")
+}
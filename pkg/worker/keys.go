@@ -0,0 +1,136 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package worker
+
+import (
+	"context"
+
+	"kubevault.dev/unsealer/pkg/kv"
+	"kubevault.dev/unsealer/pkg/vault/util"
+
+	"github.com/pkg/errors"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+)
+
+// StoredKey is a key in the key store that follows the naming scheme of the
+// unseal keys, root tokens and snapshots
+type StoredKey struct {
+	ID string
+	// Prefix is the key prefix of the cluster that stored the key
+	Prefix string
+	Kind   string
+}
+
+// ListKeys returns the keys in the key store that follow the naming scheme,
+// the keys of every key prefix are returned. Keys that do not follow the
+// naming scheme are left out.
+func (o *WorkerOptions) ListKeys(ctx context.Context) ([]StoredKey, error) {
+	w, err := o.newWorker()
+	if err != nil {
+		return nil, err
+	}
+	return w.listKeys(ctx)
+}
+
+func (w *worker) listKeys(ctx context.Context) ([]StoredKey, error) {
+	ids, err := w.keyStore.List(ctx, "")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list the key store")
+	}
+
+	var keys []StoredKey
+	for _, id := range ids {
+		if prefix, kind, ok := util.ParseKeyID(id); ok {
+			keys = append(keys, StoredKey{ID: id, Prefix: prefix, Kind: kind})
+		}
+	}
+	return keys, nil
+}
+
+// PruneKeys deletes the keys of the given key prefixes, left behind by old
+// clusters or by an old --key-prefix. The keys of the key prefix in use are
+// never deleted. The deleted keys are returned, nothing is deleted if dryRun
+// is true.
+func (o *WorkerOptions) PruneKeys(ctx context.Context, prefixes []string, dryRun bool) ([]StoredKey, error) {
+	w, err := o.newWorker()
+	if err != nil {
+		return nil, err
+	}
+	return w.pruneKeys(ctx, prefixes, dryRun)
+}
+
+func (w *worker) pruneKeys(ctx context.Context, prefixes []string, dryRun bool) ([]StoredKey, error) {
+	prune := sets.New(prefixes...)
+	if prune.Has(w.UnsealerOptions.KeyPrefix) {
+		return nil, errors.Errorf("key prefix %q is in use, its keys can not be pruned", w.UnsealerOptions.KeyPrefix)
+	}
+
+	keys, err := w.listKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pruned []StoredKey
+	var errs []error
+	for _, key := range keys {
+		if !prune.Has(key.Prefix) {
+			continue
+		}
+		if !dryRun {
+			if err := w.keyStore.Delete(ctx, key.ID); err != nil {
+				errs = append(errs, errors.Wrapf(err, "failed to delete %s", key.ID))
+				continue
+			}
+			klog.Infof("deleted %s", key.ID)
+		}
+		pruned = append(pruned, key)
+	}
+	return pruned, utilerrors.NewAggregate(errs)
+}
+
+// KeyVersions returns the versions of a key in the key store, the newest first
+func (o *WorkerOptions) KeyVersions(ctx context.Context, key string) ([]kv.Version, error) {
+	w, err := o.newWorker()
+	if err != nil {
+		return nil, err
+	}
+	return w.keyStore.Versions(ctx, key)
+}
+
+// RestoreKey sets a key to the value of one of its previous versions, the
+// restored value becomes the newest version of the key.
+func (o *WorkerOptions) RestoreKey(ctx context.Context, key, version string) error {
+	w, err := o.newWorker()
+	if err != nil {
+		return err
+	}
+	return w.restoreKey(ctx, key, version)
+}
+
+func (w *worker) restoreKey(ctx context.Context, key, version string) error {
+	value, err := w.keyStore.GetVersion(ctx, key, version)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get version %s of %s", version, key)
+	}
+	if err := w.keyStore.Set(ctx, key, value); err != nil {
+		return errors.Wrapf(err, "failed to restore %s", key)
+	}
+	klog.Infof("restored version %s of %s", version, key)
+	return nil
+}
@@ -0,0 +1,108 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package worker
+
+import (
+	"context"
+	"testing"
+
+	"kubevault.dev/unsealer/pkg/internal/kvtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPruneKeys(t *testing.T) {
+	newStore := func() *kvtest.Store {
+		return kvtest.New(map[string][]byte{
+			"vault-unseal-key-0":        []byte("a"),
+			"vault-root-token":          []byte("b"),
+			"old-unseal-key-0":          []byte("c"),
+			"old-root-token":            []byte("d"),
+			"old-snapshot-0-chunk-0":    []byte("e"),
+			"vault-unsealer-dummy-file": []byte("f"),
+		})
+	}
+
+	t.Run("lists the keys of every prefix", func(t *testing.T) {
+		w := newTestWorker(nil)
+		w.keyStore = newStore()
+
+		keys, err := w.listKeys(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []StoredKey{
+			{ID: "old-root-token", Prefix: "old", Kind: "root-token"},
+			{ID: "old-snapshot-0-chunk-0", Prefix: "old", Kind: "snapshot-chunk"},
+			{ID: "old-unseal-key-0", Prefix: "old", Kind: "unseal-key"},
+			{ID: "vault-root-token", Prefix: "vault", Kind: "root-token"},
+			{ID: "vault-unseal-key-0", Prefix: "vault", Kind: "unseal-key"},
+		}, keys)
+	})
+
+	t.Run("deletes the keys of the prefix", func(t *testing.T) {
+		store := newStore()
+		w := newTestWorker(nil)
+		w.keyStore = store
+
+		pruned, err := w.pruneKeys(context.Background(), []string{"old"}, false)
+		require.NoError(t, err)
+		assert.Len(t, pruned, 3)
+		left, _ := store.List(context.Background(), "")
+		assert.Equal(t, []string{"vault-root-token", "vault-unseal-key-0", "vault-unsealer-dummy-file"}, left)
+	})
+
+	t.Run("dry run deletes nothing", func(t *testing.T) {
+		store := newStore()
+		w := newTestWorker(nil)
+		w.keyStore = store
+
+		pruned, err := w.pruneKeys(context.Background(), []string{"old"}, true)
+		require.NoError(t, err)
+		assert.Len(t, pruned, 3)
+		assert.Len(t, store.Values(), 6)
+	})
+
+	t.Run("refuses the key prefix in use", func(t *testing.T) {
+		store := newStore()
+		w := newTestWorker(nil)
+		w.keyStore = store
+
+		_, err := w.pruneKeys(context.Background(), []string{"old", "vault"}, false)
+		assert.Error(t, err)
+		assert.Len(t, store.Values(), 6)
+	})
+}
+
+func TestRestoreKey(t *testing.T) {
+	ctx := context.Background()
+	store := kvtest.New(nil)
+	w := newTestWorker(nil)
+	w.keyStore = store
+	require.NoError(t, store.Set(ctx, "vault-unseal-key-0", []byte("good")))
+	require.NoError(t, store.Set(ctx, "vault-unseal-key-0", []byte("bad")))
+
+	require.NoError(t, w.restoreKey(ctx, "vault-unseal-key-0", "1"))
+	v, err := store.Get(ctx, "vault-unseal-key-0")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("good"), v)
+
+	versions, err := store.Versions(ctx, "vault-unseal-key-0")
+	require.NoError(t, err)
+	assert.Len(t, versions, 3, "the restored value is a new version")
+
+	assert.Error(t, w.restoreKey(ctx, "vault-unseal-key-0", "7"))
+}
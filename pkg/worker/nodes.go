@@ -0,0 +1,206 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package worker
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"kubevault.dev/unsealer/pkg/vault"
+	"kubevault.dev/unsealer/pkg/vault/unseal"
+
+	vaultapi "github.com/hashicorp/vault/api"
+	"github.com/pkg/errors"
+	discovery "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"kmodules.xyz/client-go/meta"
+)
+
+// node is a single vault server
+type node struct {
+	address  string
+	vc       *vaultapi.Client
+	unsealer unseal.Unsealer
+}
+
+// nodeStatus is the state of a vault server during a pass
+type nodeStatus struct {
+	*node
+
+	initialized bool
+	sealed      bool
+
+	// the phase in which the server failed, and why
+	phase phase
+	err   error
+}
+
+// state describes the node for the readiness endpoint
+func (s *nodeStatus) state() string {
+	switch {
+	case s.err != nil:
+		return s.err.Error()
+	case !s.initialized:
+		return "not initialized"
+	case s.sealed:
+		return "sealed"
+	default:
+		return "unsealed"
+	}
+}
+
+func (w *worker) newNode(address string) (*node, error) {
+	vc, err := vault.NewVaultClient(address, w.InsecureSkipTLSVerify, []byte(w.CaCert))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create vault api client")
+	}
+	vc.SetClientTimeout(w.VaultTimeout)
+
+	unsealer, err := unseal.New(w.keyStore, vc, *w.UnsealerOptions)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create the unsealer client")
+	}
+
+	return &node{
+		address:  address,
+		vc:       vc,
+		unsealer: unsealer,
+	}, nil
+}
+
+// vaultAddresses returns the addresses of the vault servers, they are either
+// given or discovered from the endpoints of the vault service.
+func (w *worker) vaultAddresses(ctx context.Context) ([]string, error) {
+	switch {
+	case len(w.Addresses) > 0:
+		return w.Addresses, nil
+	case w.VaultService != "":
+		return w.discoverAddresses(ctx)
+	default:
+		return []string{w.Address}, nil
+	}
+}
+
+// vaultNodes returns the vault servers of this pass, sorted by address.
+// Clients are kept for the servers that were seen in the last pass.
+func (w *worker) vaultNodes(ctx context.Context) ([]*node, error) {
+	addresses, err := w.vaultAddresses(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(addresses) == 0 {
+		return nil, errors.New("no vault server found")
+	}
+
+	nodes := make(map[string]*node, len(addresses))
+	for _, address := range addresses {
+		n, ok := w.nodes[address]
+		if !ok {
+			if n, err = w.newNode(address); err != nil {
+				return nil, errors.Wrapf(err, "vault %s", address)
+			}
+			klog.Infof("managing vault server %s", address)
+		}
+		nodes[address] = n
+	}
+	for address := range w.nodes {
+		if _, ok := nodes[address]; !ok {
+			klog.Infof("vault server %s is gone", address)
+			w.metrics.ForgetServer(address)
+		}
+	}
+	w.nodes = nodes
+
+	list := make([]*node, 0, len(nodes))
+	for _, n := range nodes {
+		list = append(list, n)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].address < list[j].address
+	})
+	return list, nil
+}
+
+// discoverAddresses lists the endpoint slices of the vault service. Endpoints
+// that are not ready are included, since a sealed vault server is not ready.
+// An endpoint with a hostname, as a pod of a StatefulSet behind a headless
+// service has, is addressed by its DNS name so that its certificate can be
+// verified.
+func (w *worker) discoverAddresses(ctx context.Context) ([]string, error) {
+	namespace, name := meta.PodNamespace(), w.VaultService
+	if ns, n, ok := strings.Cut(w.VaultService, "/"); ok {
+		namespace, name = ns, n
+	}
+
+	slices, err := w.kubeClient.DiscoveryV1().EndpointSlices(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: discovery.LabelServiceName + "=" + name,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list the endpoints of service %s/%s", namespace, name)
+	}
+
+	seen := map[string]bool{}
+	var addresses []string
+	for _, slice := range slices.Items {
+		port, ok := servicePort(slice.Ports, w.VaultServicePort)
+		if !ok {
+			continue
+		}
+
+		for _, ep := range slice.Endpoints {
+			if ep.Conditions.Terminating != nil && *ep.Conditions.Terminating {
+				continue
+			}
+
+			var host string
+			switch {
+			case ep.Hostname != nil:
+				host = fmt.Sprintf("%s.%s.%s.svc", *ep.Hostname, name, namespace)
+			case len(ep.Addresses) > 0:
+				host = ep.Addresses[0]
+			default:
+				continue
+			}
+
+			address := fmt.Sprintf("%s://%s", w.VaultServiceScheme, net.JoinHostPort(host, strconv.Itoa(int(port))))
+			if !seen[address] {
+				seen[address] = true
+				addresses = append(addresses, address)
+			}
+		}
+	}
+	return addresses, nil
+}
+
+// servicePort returns the port with the given name, or the first port if name
+// is empty.
+func servicePort(ports []discovery.EndpointPort, name string) (int32, bool) {
+	for _, p := range ports {
+		if p.Port == nil {
+			continue
+		}
+		if name == "" || (p.Name != nil && *p.Name == name) {
+			return *p.Port, true
+		}
+	}
+	return 0, false
+}
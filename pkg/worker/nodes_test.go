@@ -0,0 +1,178 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"kubevault.dev/unsealer/pkg/events"
+	"kubevault.dev/unsealer/pkg/internal/kvtest"
+	"kubevault.dev/unsealer/pkg/metrics"
+	"kubevault.dev/unsealer/pkg/vault/unseal"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeUnsealer struct {
+	mu sync.Mutex
+
+	unreachable bool
+	initialized bool
+	sealed      bool
+	calls       []string
+	// the server shares the storage of another server, it is initialized
+	// once the other one is
+	shared *fakeUnsealer
+}
+
+func (f *fakeUnsealer) call(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, name)
+}
+
+func (f *fakeUnsealer) IsSealed(ctx context.Context) (bool, error) {
+	return f.sealed, nil
+}
+
+func (f *fakeUnsealer) IsInitialized(ctx context.Context) (bool, error) {
+	if f.unreachable {
+		return false, errors.New("connection refused")
+	}
+	if f.shared != nil {
+		return f.shared.initialized, nil
+	}
+	return f.initialized, nil
+}
+
+func (f *fakeUnsealer) Unseal(ctx context.Context) error {
+	f.call("unseal")
+	f.sealed = false
+	return nil
+}
+
+func (f *fakeUnsealer) Init(ctx context.Context) error {
+	f.call("init")
+	f.initialized, f.sealed = true, true
+	return nil
+}
+
+func (f *fakeUnsealer) CheckReadWriteAccess(ctx context.Context) error {
+	return nil
+}
+
+func (f *fakeUnsealer) Rekey(ctx context.Context, opts unseal.RekeyOptions) error {
+	return nil
+}
+
+func (f *fakeUnsealer) GenerateRootToken(ctx context.Context) (string, error) {
+	return "", errors.New("not implemented")
+}
+
+func (f *fakeUnsealer) FinishSealMigration(ctx context.Context) error {
+	return nil
+}
+
+func (f *fakeUnsealer) JoinRaft(ctx context.Context, leaderAPIAddr, leaderCACert string) error {
+	f.call("join " + leaderAPIAddr)
+	f.initialized, f.sealed = true, true
+	return nil
+}
+
+func newTestWorker(unsealers map[string]*fakeUnsealer) *worker {
+	o := NewWorkerOptions()
+	w := &worker{
+		WorkerOptions: o,
+		keyStore:      kvtest.New(nil),
+		metrics:       metrics.New("test"),
+		health:        newHealth(o.LivenessWindow),
+		recorder:      events.NewNopRecorder(),
+		nodes:         map[string]*node{},
+	}
+	for address, u := range unsealers {
+		o.Addresses = append(o.Addresses, address)
+		w.nodes[address] = &node{address: address, unsealer: u}
+	}
+	return w
+}
+
+func TestReconcileNodes(t *testing.T) {
+	t.Run("initializes only the first server, the others join it", func(t *testing.T) {
+		a, b := &fakeUnsealer{}, &fakeUnsealer{}
+		w := newTestWorker(map[string]*fakeUnsealer{"https://vault-0:8200": a, "https://vault-1:8200": b})
+
+		res, err := w.reconcile(context.Background())
+		assert.Error(t, err, "configure fails without a root token")
+		assert.Equal(t, phaseConfigure, res.phase)
+		assert.True(t, res.initialized)
+		assert.True(t, res.unsealed)
+		assert.Equal(t, []string{"init", "unseal"}, a.calls)
+		assert.Equal(t, []string{"join https://vault-0:8200", "unseal"}, b.calls)
+		assert.Equal(t, map[string]string{"https://vault-0:8200": "unsealed", "https://vault-1:8200": "unsealed"}, res.nodes)
+	})
+
+	t.Run("servers that share the storage do not join", func(t *testing.T) {
+		a := &fakeUnsealer{}
+		b := &fakeUnsealer{sealed: true, shared: a}
+		w := newTestWorker(map[string]*fakeUnsealer{"https://vault-0:8200": a, "https://vault-1:8200": b})
+
+		res, _ := w.reconcile(context.Background())
+		assert.True(t, res.initialized)
+		assert.Equal(t, []string{"init", "unseal"}, a.calls)
+		assert.Equal(t, []string{"unseal"}, b.calls)
+	})
+
+	t.Run("joins the configured raft leader", func(t *testing.T) {
+		a := &fakeUnsealer{initialized: true}
+		b := &fakeUnsealer{}
+		w := newTestWorker(map[string]*fakeUnsealer{"https://vault-0:8200": a, "https://vault-1:8200": b})
+		w.RaftOptions.LeaderAPIAddress = "https://vault-active:8200"
+
+		res, err := w.reconcile(context.Background())
+		assert.NoError(t, err)
+		assert.True(t, res.unsealed)
+		assert.Equal(t, []string{"join https://vault-active:8200", "unseal"}, b.calls)
+	})
+
+	t.Run("unseals every sealed server", func(t *testing.T) {
+		a := &fakeUnsealer{initialized: true, sealed: true}
+		b := &fakeUnsealer{initialized: true, sealed: true}
+		c := &fakeUnsealer{initialized: true}
+		w := newTestWorker(map[string]*fakeUnsealer{"https://vault-0:8200": a, "https://vault-1:8200": b, "https://vault-2:8200": c})
+
+		res, _ := w.reconcile(context.Background())
+		assert.False(t, res.initialized)
+		assert.True(t, res.unsealed)
+		assert.Equal(t, []string{"unseal"}, a.calls)
+		assert.Equal(t, []string{"unseal"}, b.calls)
+		assert.Empty(t, c.calls)
+	})
+
+	t.Run("does not initialize while a server is unreachable", func(t *testing.T) {
+		a, b := &fakeUnsealer{}, &fakeUnsealer{unreachable: true}
+		w := newTestWorker(map[string]*fakeUnsealer{"https://vault-0:8200": a, "https://vault-1:8200": b})
+
+		res, err := w.reconcile(context.Background())
+		assert.ErrorIs(t, err, errVaultUnreachable)
+		assert.Equal(t, phaseInit, res.phase)
+		assert.Empty(t, a.calls)
+		assert.Equal(t, ExitVaultUnreachable, exitCodeFor(res, err))
+	})
+}
@@ -0,0 +1,121 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package worker
+
+import (
+	"context"
+
+	"kubevault.dev/unsealer/pkg/events"
+	"kubevault.dev/unsealer/pkg/vault/unseal"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+)
+
+// ExitCode is the exit code of a one-shot run
+type ExitCode int
+
+const (
+	// vault was already initialized, unsealed and is configured
+	ExitHealthy ExitCode = 0
+	// any failure that has no code of its own
+	ExitFailed ExitCode = 1
+	// vault was initialized, and unsealed
+	ExitInitialized ExitCode = 10
+	// vault was unsealed
+	ExitUnsealed ExitCode = 11
+	// the key store could not be read or written
+	ExitKeyStoreUnreachable ExitCode = 20
+	// the status of vault could not be read
+	ExitVaultUnreachable ExitCode = 21
+	// vault is unsealed, but it could not be configured
+	ExitConfigureFailed ExitCode = 22
+)
+
+func (c ExitCode) String() string {
+	switch c {
+	case ExitHealthy:
+		return "healthy"
+	case ExitInitialized:
+		return "initialized"
+	case ExitUnsealed:
+		return "unsealed"
+	case ExitKeyStoreUnreachable:
+		return "keystore unreachable"
+	case ExitVaultUnreachable:
+		return "vault unreachable"
+	case ExitConfigureFailed:
+		return "configure failed"
+	default:
+		return "failed"
+	}
+}
+
+// RunOnce makes a single reconcile pass without retries, and returns the exit
+// code that describes its outcome.
+func (o *WorkerOptions) RunOnce(ctx context.Context) (ExitCode, error) {
+	w, err := o.newWorker()
+	if err != nil {
+		return ExitFailed, err
+	}
+
+	if o.EventOptions.Enabled {
+		// keep the recorder until the pass is finished, not just until the
+		// shutdown signal arrives
+		bgCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		defer cancel()
+
+		w.recorder = events.NewRecorder(bgCtx, w.kubeClient, o.EventOptions)
+	}
+
+	// a previous run may have failed to configure vault after unsealing it,
+	// so every run configures vault and the exit code tells whether it is
+	w.configurePending = w.isLeader()
+
+	res, err := w.reconcile(ctx)
+	for address, state := range res.nodes {
+		klog.Infof("vault server %s: %s", address, state)
+	}
+	code := exitCodeFor(res, err)
+	if err != nil {
+		return code, errors.Wrapf(err, "%s phase failed", res.phase)
+	}
+
+	klog.Infof("one-shot run finished, vault is %s", code)
+	return code, nil
+}
+
+// exitCodeFor maps the outcome of a reconcile pass to an exit code. Initializing
+// vault is always followed by unsealing it, so it is reported as initialized.
+func exitCodeFor(res result, err error) ExitCode {
+	switch {
+	case err == nil && res.initialized:
+		return ExitInitialized
+	case err == nil && res.unsealed:
+		return ExitUnsealed
+	case err == nil:
+		return ExitHealthy
+	case errors.Is(err, unseal.ErrKeyStoreUnreachable), errors.Is(err, unseal.ErrKeysNotStored):
+		return ExitKeyStoreUnreachable
+	case errors.Is(err, errVaultUnreachable):
+		return ExitVaultUnreachable
+	case res.phase == phaseConfigure:
+		return ExitConfigureFailed
+	default:
+		return ExitFailed
+	}
+}
@@ -0,0 +1,88 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package worker
+
+import (
+	"errors"
+	"testing"
+
+	"kubevault.dev/unsealer/pkg/vault/unseal"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+func TestExitCodeFor(t *testing.T) {
+	cases := []struct {
+		testName string
+		res      result
+		err      error
+		expected ExitCode
+	}{
+		{
+			testName: "nothing to do",
+			expected: ExitHealthy,
+		},
+		{
+			testName: "initialized and unsealed",
+			res:      result{initialized: true, unsealed: true},
+			expected: ExitInitialized,
+		},
+		{
+			testName: "unsealed",
+			res:      result{unsealed: true},
+			expected: ExitUnsealed,
+		},
+		{
+			testName: "key store unreachable",
+			res:      result{phase: phaseInit},
+			err:      pkgerrors.Wrap(unseal.ErrKeyStoreUnreachable, "read/write access test failed"),
+			expected: ExitKeyStoreUnreachable,
+		},
+		{
+			testName: "keys not stored",
+			res:      result{phase: phaseInit},
+			err:      pkgerrors.Wrap(unseal.ErrKeysNotStored, "failed to store the root token"),
+			expected: ExitKeyStoreUnreachable,
+		},
+		{
+			testName: "vault unreachable",
+			res:      result{phase: phaseUnseal},
+			err:      pkgerrors.Wrap(errVaultUnreachable, "failed to get the sealed status"),
+			expected: ExitVaultUnreachable,
+		},
+		{
+			testName: "configure failed",
+			res:      result{phase: phaseConfigure, unsealed: true},
+			err:      errors.New("failed to enable kubernetes auth"),
+			expected: ExitConfigureFailed,
+		},
+		{
+			testName: "unseal rejected",
+			res:      result{phase: phaseUnseal},
+			err:      errors.New("failed to unseal the vault, progress is reset to 0"),
+			expected: ExitFailed,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.testName, func(t *testing.T) {
+			if got := exitCodeFor(c.res, c.err); got != c.expected {
+				t.Errorf("exit code: expected %d (%s), got %d (%s)", c.expected, c.expected, got, got)
+			}
+		})
+	}
+}
@@ -19,27 +19,37 @@ package worker
 import (
 	"time"
 
-	aws "kubevault.dev/unsealer/pkg/kv/aws_kms"
-	"kubevault.dev/unsealer/pkg/kv/azure"
-	google "kubevault.dev/unsealer/pkg/kv/cloudkms"
-	"kubevault.dev/unsealer/pkg/kv/kubernetes"
+	"kubevault.dev/unsealer/pkg/backoff"
+	"kubevault.dev/unsealer/pkg/config"
+	"kubevault.dev/unsealer/pkg/events"
+	"kubevault.dev/unsealer/pkg/kv/backend"
+	_ "kubevault.dev/unsealer/pkg/kv/backend/all"
+	"kubevault.dev/unsealer/pkg/kv/placement"
+	"kubevault.dev/unsealer/pkg/leader"
+	"kubevault.dev/unsealer/pkg/snapshot"
 	"kubevault.dev/unsealer/pkg/vault/auth"
 	"kubevault.dev/unsealer/pkg/vault/policy"
 	"kubevault.dev/unsealer/pkg/vault/unseal"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/util/sets"
 )
 
 const (
-	ModeGoogleCloudKmsGCS = "google-cloud-kms-gcs"
-	ModeAwsKmsSsm         = "aws-kms-ssm"
-	ModeAzureKeyVault     = "azure-key-vault"
-	ModeKubernetesSecret  = "kubernetes-secret"
-
-	VaultAddressDefault = "https://127.0.0.1:8200"
+	VaultAddressDefault       = "https://127.0.0.1:8200"
+	VaultServiceSchemeDefault = "https"
+	VaultConcurrencyDefault   = 3
+	HTTPAddressDefault        = ":8080"
 
 	RetryPeriod = 10 * time.Second
+
+	LivenessWindowDefault = 5 * time.Minute
+
+	AdminTokenPeriodDefault = 768 * time.Hour
+
+	VaultTimeoutDefault    = 60 * time.Second
+	KeyStoreTimeoutDefault = 30 * time.Second
 )
 
 type WorkerOptions struct {
@@ -47,6 +57,20 @@ type WorkerOptions struct {
 	// Address form : scheme://host:port
 	Address string
 
+	// Addresses of all the vault servers of a cluster, it takes precedence over Address
+	Addresses []string
+
+	// Name of the service whose endpoints are the vault servers, in the form
+	// [namespace/]name. The namespace defaults to the namespace of the pod.
+	VaultService string
+	// name of the service port of the vault api, the first port is used if it is empty
+	VaultServicePort string
+	// scheme used for the discovered vault servers
+	VaultServiceScheme string
+
+	// maximum number of vault servers that are checked or unsealed at the same time
+	Concurrency int
+
 	// ca cert for vault api client, if vault used a self signed certificate
 	CaCert string
 
@@ -56,77 +80,195 @@ type WorkerOptions struct {
 	// retry period to try initializing and unsealing
 	ReTryPeriod time.Duration
 
-	// Select the mode to use
-	// 	- 'google-cloud-kms-gcs' => Google Cloud Storage with encryption using Google KMS
-	// 	- 'aws-kms-ssm' => AWS SSM parameter store using AWS KMS encryption
-	//  - 'azure-key-vault' => Azure Key Vault Secret store
-	//  - 'kubernetes-secret' => Kubernetes secret to store unseal keys
+	// timeout for each request made to the vault api
+	VaultTimeout time.Duration
+
+	// timeout for each call made to the key store
+	KeyStoreTimeout time.Duration
+
+	// address of the http server that exposes the metrics and the health
+	// endpoints, the server is disabled when it is empty
+	HTTPAddress string
+
+	// the liveness check fails if no reconcile pass completed within this window
+	LivenessWindow time.Duration
+
+	// make a single pass and exit with a code that describes its outcome
+	OneShot bool
+
+	// generate a root token with the stored keys whenever vault is configured
+	// and revoke it afterwards, instead of reading a stored root token
+	GenerateRootToken bool
+
+	// replace the stored root token by an orphan periodic token that can only
+	// configure vault, once vault is configured, and revoke the root token
+	ScopeAdminToken bool
+	// period of the scoped admin token, it is renewed once half of it is left
+	AdminTokenPeriod time.Duration
+
+	// path of the config file, flags given on the command line and
+	// VAULT_UNSEALER_* environment variables take precedence over it
+	ConfigFile string
+	// set by LoadConfig
+	config *config.Loader
+
+	// Select the mode to use, a registered storage backend or a
+	// storage+encryption pair like 'gcs+awskms'. The modes of earlier
+	// releases, like 'aws-kms-ssm', are aliases.
 	Mode string
 
-	AuthenticatorOptions *auth.K8sAuthenticatorOptions
-	UnsealerOptions      *unseal.UnsealOptions
-	PolicyManagerOptions *policy.PolicyManagerOptions
-	GoogleOptions        *google.Options
-	AwsOptions           *aws.Options
-	AzureOptions         *azure.Options
-	KubernetesOptions    *kubernetes.Options
+	// place ranges of the unseal and recovery shares in the stores of other
+	// modes, e.g. '0-1=aws-kms-ssm'. Shares that are not placed, and every
+	// other key, are stored in the store of the selected mode.
+	SharePlacement []string
+
+	BackoffOptions        *backoff.Options
+	LeaderElectionOptions *leader.Options
+	EventOptions          *events.Options
+	AuthenticatorOptions  *auth.K8sAuthenticatorOptions
+	UnsealerOptions       *unseal.UnsealOptions
+	RaftOptions           *unseal.RaftOptions
+	SnapshotOptions       *snapshot.Options
+	PolicyManagerOptions  *policy.PolicyManagerOptions
+	// options of every registered key store backend
+	BackendOptions backend.OptionSet
 }
 
 func NewWorkerOptions() *WorkerOptions {
 	return &WorkerOptions{
-		Address:              VaultAddressDefault,
-		ReTryPeriod:          RetryPeriod,
-		UnsealerOptions:      unseal.NewUnsealOptions(),
-		AuthenticatorOptions: auth.NewK8sAuthOptions(),
-		PolicyManagerOptions: policy.NewPolicyOptions(),
-		GoogleOptions:        google.NewOptions(),
-		AwsOptions:           aws.NewOptions(),
-		AzureOptions:         azure.NewOptions(),
-		KubernetesOptions:    kubernetes.NewOptions(),
+		Address:               VaultAddressDefault,
+		VaultServiceScheme:    VaultServiceSchemeDefault,
+		Concurrency:           VaultConcurrencyDefault,
+		HTTPAddress:           HTTPAddressDefault,
+		LivenessWindow:        LivenessWindowDefault,
+		ReTryPeriod:           RetryPeriod,
+		VaultTimeout:          VaultTimeoutDefault,
+		KeyStoreTimeout:       KeyStoreTimeoutDefault,
+		AdminTokenPeriod:      AdminTokenPeriodDefault,
+		BackoffOptions:        backoff.NewOptions(),
+		LeaderElectionOptions: leader.NewOptions(),
+		EventOptions:          events.NewOptions(),
+		UnsealerOptions:       unseal.NewUnsealOptions(),
+		RaftOptions:           unseal.NewRaftOptions(),
+		SnapshotOptions:       snapshot.NewOptions(),
+		AuthenticatorOptions:  auth.NewK8sAuthOptions(),
+		PolicyManagerOptions:  policy.NewPolicyOptions(),
+		BackendOptions:        backend.NewOptionSet(),
 	}
 }
 
 func (o *WorkerOptions) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&o.Address, "vault.address", o.Address, "Specifies the vault address. Address form : scheme://host:port")
+	fs.StringSliceVar(&o.Addresses, "vault.addresses", o.Addresses, "Addresses of all the vault servers of a cluster, e.g. the pods of a raft cluster. Only one of them is initialized, all of them are unsealed. Takes precedence over --vault.address")
+	fs.StringVar(&o.VaultService, "vault.service", o.VaultService, "Discover the vault servers from the endpoints of this service, in the form [namespace/]name. Endpoints that are not ready are included")
+	fs.StringVar(&o.VaultServicePort, "vault.service-port", o.VaultServicePort, "Name of the vault api port of the service. The first port is used if it is empty")
+	fs.StringVar(&o.VaultServiceScheme, "vault.service-scheme", o.VaultServiceScheme, "Scheme used to talk to the discovered vault servers")
+	fs.IntVar(&o.Concurrency, "vault.concurrency", o.Concurrency, "Maximum number of vault servers that are checked or unsealed at the same time")
 	fs.StringVar(&o.CaCert, "vault.ca-cert", o.CaCert, "Specifies the CA cert that will be used to verify self signed vault server certificate")
 	fs.BoolVar(&o.InsecureSkipTLSVerify, "vault.insecure-skip-tls-verify", o.InsecureSkipTLSVerify, "To skip tls verification when communicating with vault server")
-	fs.StringVar(&o.Mode, "mode", o.Mode, "Select the mode to use 'google-cloud-kms-gcs' => Google Cloud Storage with encryption using Google KMS; 'aws-kms-ssm' => AWS SSM parameter store using AWS KMS; 'azure-key-vault' => Azure Key Vault Secret store; 'kubernetes-secret' => Kubernetes secret to store unseal keys")
-	fs.DurationVar(&o.ReTryPeriod, "retry-period", o.ReTryPeriod, "How often to attempt to unseal the vault instance")
+	fs.StringVar(&o.Mode, "mode", o.Mode, "Select the mode to use, a storage or a storage+encryption pair like 'gcs+awskms'. "+backend.Help())
+	fs.StringSliceVar(&o.SharePlacement, "share-placement", o.SharePlacement, "Place ranges of the unseal and recovery shares in the stores of other modes, e.g. '0-1=aws-kms-ssm,2-3=google-cloud-kms-gcs,4=kubernetes-secret'. The options of every mode used apply, shares that are not placed and the root token are stored in the store of --mode")
+	fs.DurationVar(&o.ReTryPeriod, "retry-period", o.ReTryPeriod, "How often to check that the vault instance is initialized and unsealed, failures are retried using the backoff settings")
+	fs.DurationVar(&o.VaultTimeout, "vault.timeout", o.VaultTimeout, "Timeout for each request made to the vault server. Zero means no timeout")
+	fs.StringVar(&o.HTTPAddress, "http-address", o.HTTPAddress, "Address of the http server that serves /metrics, /healthz and /readyz. Set it to empty to disable the server")
+	fs.DurationVar(&o.LivenessWindow, "liveness-window", o.LivenessWindow, "/healthz fails if no reconcile pass completed within this window")
+	fs.DurationVar(&o.KeyStoreTimeout, "keystore-timeout", o.KeyStoreTimeout, "Timeout for each call made to the key store. Zero means no timeout")
+	fs.StringVar(&o.ConfigFile, "config", o.ConfigFile, "Path of a YAML or JSON config file that sets any of these flags by name. Flags given on the command line take precedence over VAULT_UNSEALER_<FLAG> environment variables, which take precedence over the file. Retry, backoff, auth and policy settings are reloaded when the file changes. Can also be set by VAULT_UNSEALER_CONFIG")
+	fs.BoolVar(&o.GenerateRootToken, "generate-root-token", o.GenerateRootToken, "Generate a root token with the stored unseal or recovery keys whenever vault is configured, and revoke it afterwards. Requires --store-root-token=false and vault 1.10 or later")
+	fs.BoolVar(&o.ScopeAdminToken, "scope-admin-token", o.ScopeAdminToken, "Once vault is configured, replace the stored root token by an orphan periodic token that can only configure vault, and revoke the root token. Anything else that reads the stored root token loses access. The token is limited to the policy and role of --policy-manager.name, renaming it needs a root token")
+	fs.DurationVar(&o.AdminTokenPeriod, "admin-token-period", o.AdminTokenPeriod, "Period of the scoped admin token, it is renewed once half of the period is left")
+	fs.BoolVar(&o.OneShot, "one-shot", o.OneShot, "Make a single init, unseal and configure pass without retries and exit. Exit codes: 0 already healthy, 10 initialized, 11 unsealed, 20 keystore unreachable, 21 vault unreachable, 22 configure failed, 1 any other failure")
 
+	o.BackoffOptions.AddFlags(fs)
+	o.LeaderElectionOptions.AddFlags(fs)
+	o.EventOptions.AddFlags(fs)
 	o.UnsealerOptions.AddFlags(fs)
+	o.RaftOptions.AddFlags(fs)
+	o.SnapshotOptions.AddFlags(fs)
 	o.AuthenticatorOptions.AddFlags(fs)
 	o.PolicyManagerOptions.AddFlags(fs)
-	o.GoogleOptions.AddFlags(fs)
-	o.AwsOptions.AddFlags(fs)
-	o.AzureOptions.AddFlags(fs)
-	o.KubernetesOptions.AddFlags(fs)
+	o.BackendOptions.AddFlags(fs)
 }
 
 func (o *WorkerOptions) Validate() []error {
-	var errs []error
-	if o.Mode != ModeGoogleCloudKmsGCS &&
-		o.Mode != ModeAwsKmsSsm &&
-		o.Mode != ModeKubernetesSecret &&
-		o.Mode != ModeAzureKeyVault {
-		errs = append(errs, errors.New("invalid mode"))
+	errs := o.ValidateClients()
+	if o.LivenessWindow <= o.ReTryPeriod || o.LivenessWindow <= o.BackoffOptions.MaxDelay {
+		errs = append(errs, errors.New("liveness window must be greater than the retry period and the backoff max delay"))
+	}
+	if o.GenerateRootToken && o.UnsealerOptions.StoreRootToken {
+		errs = append(errs, errors.New("generate root token can not be used with store-root-token, no root token is stored"))
+	}
+	if o.GenerateRootToken && len(o.UnsealerOptions.PGPKeys) > 0 {
+		errs = append(errs, errors.New("generate root token can not be used with pgp keys, the stored keys are encrypted"))
+	}
+	if o.ScopeAdminToken && o.AdminTokenPeriod < 2*o.ReTryPeriod {
+		errs = append(errs, errors.New("admin token period must be at least twice the retry period"))
+	}
+	if o.OneShot && o.SnapshotOptions.Schedule != "" {
+		errs = append(errs, errors.New("one-shot mode can not take scheduled snapshots"))
+	}
+	if o.OneShot && o.LeaderElectionOptions.Enabled {
+		errs = append(errs, errors.New("one-shot mode can not be used with leader election"))
 	}
 
-	errs = append(errs, o.UnsealerOptions.Validate()...)
+	errs = append(errs, o.BackoffOptions.Validate()...)
+	errs = append(errs, o.LeaderElectionOptions.Validate()...)
+	errs = append(errs, o.EventOptions.Validate()...)
 	errs = append(errs, o.AuthenticatorOptions.Validate()...)
 	errs = append(errs, o.PolicyManagerOptions.Validate()...)
 
-	if o.Mode == ModeGoogleCloudKmsGCS {
-		errs = append(errs, o.GoogleOptions.Validate()...)
+	return errs
+}
+
+// ValidateClients validates only the options that are needed to talk to vault
+// and the key store, which is all that the commands other than run need.
+func (o *WorkerOptions) ValidateClients() []error {
+	var errs []error
+	modes := sets.New(o.Mode)
+	rules, err := placement.ParseRules(o.SharePlacement)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for _, rule := range rules {
+		modes.Insert(rule.Mode)
+	}
+	// modes that share a backend report the errors of its options once
+	reported := sets.New[string]()
+	for _, mode := range sets.List(modes) {
+		m, err := backend.Parse(mode, o.BackendOptions)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, err := range m.Validate(o.BackendOptions) {
+			if !reported.Has(err.Error()) {
+				reported.Insert(err.Error())
+				errs = append(errs, err)
+			}
+		}
+		if mode == o.Mode && m.Storage.NoSnapshots && o.SnapshotOptions.Schedule != "" {
+			errs = append(errs, errors.Errorf("snapshots can not be stored in %s mode, the %s storage is too small", mode, m.Storage.Name))
+		}
 	}
-	if o.Mode == ModeAwsKmsSsm {
-		errs = append(errs, o.AwsOptions.Validate()...)
+	if len(o.Addresses) > 0 && o.VaultService != "" {
+		errs = append(errs, errors.New("vault addresses and vault service can not be used together"))
 	}
-	if o.Mode == ModeAzureKeyVault {
-		errs = append(errs, o.AzureOptions.Validate()...)
+	if o.VaultServiceScheme != "http" && o.VaultServiceScheme != "https" {
+		errs = append(errs, errors.New("vault service scheme must be http or https"))
 	}
-	if o.Mode == ModeKubernetesSecret {
-		errs = append(errs, o.KubernetesOptions.Validate()...)
+	if o.Concurrency < 1 {
+		errs = append(errs, errors.New("vault concurrency must be at least 1"))
 	}
+	if o.VaultTimeout < 0 {
+		errs = append(errs, errors.New("vault timeout must not be negative"))
+	}
+	if o.KeyStoreTimeout < 0 {
+		errs = append(errs, errors.New("keystore timeout must not be negative"))
+	}
+
+	errs = append(errs, o.UnsealerOptions.Validate()...)
+	errs = append(errs, o.RaftOptions.Validate()...)
+	errs = append(errs, o.SnapshotOptions.Validate()...)
 
 	return errs
 }
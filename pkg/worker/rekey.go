@@ -0,0 +1,73 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package worker
+
+import (
+	"context"
+
+	"kubevault.dev/unsealer/pkg/vault/unseal"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+)
+
+// Rekey rotates the unseal keys of vault and stores the new keys in the key
+// store. The keys belong to the whole cluster, so it is rekeyed through the
+// active vault server.
+func (o *WorkerOptions) Rekey(ctx context.Context, ro *unseal.RekeyOptions) error {
+	w, err := o.newWorker()
+	if err != nil {
+		return err
+	}
+
+	nodes, err := w.vaultNodes(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to find the vault servers")
+	}
+
+	n, err := activeNode(ctx, nodes)
+	if err != nil {
+		return err
+	}
+
+	klog.Infof("rekeying vault through %s", n.address)
+	if err := n.unsealer.Rekey(ctx, *ro); err != nil {
+		return errors.Wrap(err, "failed to rekey vault")
+	}
+
+	klog.Infof("vault is rekeyed, use --secret-shares=%d --secret-threshold=%d from now on", ro.SecretShares, ro.SecretThreshold)
+	return nil
+}
+
+// activeNode returns the vault server that is unsealed and active, standby
+// servers and servers that can not be reached are skipped
+func activeNode(ctx context.Context, nodes []*node) (*node, error) {
+	for _, n := range nodes {
+		if sealed, err := n.unsealer.IsSealed(ctx); err != nil || sealed {
+			continue
+		}
+		leader, err := n.vc.Sys().LeaderWithContext(ctx)
+		if err != nil {
+			klog.Warningf("skipping vault server %s, failed to get its leader status with %s", n.address, err.Error())
+			continue
+		}
+		if !leader.HAEnabled || leader.IsSelf {
+			return n, nil
+		}
+	}
+	return nil, errors.New("no vault server is unsealed and active")
+}
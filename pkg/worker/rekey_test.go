@@ -0,0 +1,73 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"kubevault.dev/unsealer/pkg/vault"
+
+	vaultapi "github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+)
+
+// newLeaderNode returns a vault server that reports the given leader status
+func newLeaderNode(t *testing.T, address string, sealed bool, leader vaultapi.LeaderResponse) *node {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		utilruntime.Must(json.NewEncoder(w).Encode(&leader))
+	}))
+	t.Cleanup(srv.Close)
+
+	vc, err := vault.NewVaultClient(srv.URL, true, nil)
+	require.NoError(t, err)
+	return &node{address: address, vc: vc, unsealer: &fakeUnsealer{initialized: true, sealed: sealed}}
+}
+
+func TestActiveNode(t *testing.T) {
+	t.Run("sealed and standby servers are skipped", func(t *testing.T) {
+		nodes := []*node{
+			newLeaderNode(t, "https://vault-0:8200", true, vaultapi.LeaderResponse{HAEnabled: true, IsSelf: true}),
+			newLeaderNode(t, "https://vault-1:8200", false, vaultapi.LeaderResponse{HAEnabled: true}),
+			newLeaderNode(t, "https://vault-2:8200", false, vaultapi.LeaderResponse{HAEnabled: true, IsSelf: true}),
+		}
+
+		n, err := activeNode(context.Background(), nodes)
+		require.NoError(t, err)
+		assert.Equal(t, "https://vault-2:8200", n.address)
+	})
+
+	t.Run("an unsealed server without ha is active", func(t *testing.T) {
+		nodes := []*node{newLeaderNode(t, "https://vault-0:8200", false, vaultapi.LeaderResponse{})}
+
+		n, err := activeNode(context.Background(), nodes)
+		require.NoError(t, err)
+		assert.Equal(t, "https://vault-0:8200", n.address)
+	})
+
+	t.Run("no server is active", func(t *testing.T) {
+		nodes := []*node{newLeaderNode(t, "https://vault-0:8200", false, vaultapi.LeaderResponse{HAEnabled: true})}
+
+		_, err := activeNode(context.Background(), nodes)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,111 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package worker
+
+import (
+	"context"
+	"os"
+	"reflect"
+
+	"kubevault.dev/unsealer/pkg/backoff"
+	"kubevault.dev/unsealer/pkg/config"
+
+	"github.com/spf13/pflag"
+	utilerrors "gomodules.xyz/errors"
+	"k8s.io/klog/v2"
+)
+
+// LoadConfig sets the options that were not given on the command line from
+// the environment and the config file. cmdline must be the parsed flag set the
+// options were added to.
+func (o *WorkerOptions) LoadConfig(cmdline *pflag.FlagSet) error {
+	if o.ConfigFile == "" {
+		o.ConfigFile = os.Getenv(config.EnvName("config"))
+	}
+	o.config = config.NewLoader(cmdline, o.ConfigFile)
+
+	// only the worker options are read from the config file, so the flag set
+	// is built the same way as on reload
+	fs := pflag.NewFlagSet("config", pflag.ContinueOnError)
+	o.AddFlags(fs)
+	return o.config.Load(fs)
+}
+
+// watchConfig reloads the config file when it changes. The reloaded options
+// are validated and handed to the worker, which applies them before its next
+// pass.
+func (w *worker) watchConfig(ctx context.Context) error {
+	w.reloads = make(chan *WorkerOptions, 1)
+
+	return w.config.Watch(ctx, func() {
+		klog.Infof("config file %s changed, reloading", w.config.Path())
+
+		n := NewWorkerOptions()
+		fs := pflag.NewFlagSet("config", pflag.ContinueOnError)
+		n.AddFlags(fs)
+		if err := w.config.Load(fs); err != nil {
+			klog.Errorf("failed to reload the config file with %s", err.Error())
+			return
+		}
+		if errs := n.Validate(); errs != nil {
+			klog.Errorf("ignoring the changed config file with %s", utilerrors.NewAggregate(errs).Error())
+			return
+		}
+
+		// only the latest options are kept
+		select {
+		case <-w.reloads:
+		default:
+		}
+		w.reloads <- n
+	})
+}
+
+// applyReload applies the settings that can be changed without a restart:
+// the retry period, the backoff, and the auth and policy options used to
+// configure vault. Vault is configured again if the auth or policy options
+// changed. Changes to other settings take effect after a restart.
+//
+// The scoped admin token may only write the policy and role of the policy
+// manager it was created for, so a reload that renames the policy manager is
+// rejected while the admin token is scoped.
+func (w *worker) applyReload() {
+	var n *WorkerOptions
+	select {
+	case n = <-w.reloads:
+	default:
+		return
+	}
+
+	if w.ScopeAdminToken && !w.GenerateRootToken && n.PolicyManagerOptions.Name != w.PolicyManagerOptions.Name {
+		klog.Errorf("ignoring the changed config file, the scoped admin token only allows the policy manager %s. Store a root token or use --generate-root-token to rename it", w.PolicyManagerOptions.Name)
+		return
+	}
+
+	if !reflect.DeepEqual(w.AuthenticatorOptions, n.AuthenticatorOptions) || !reflect.DeepEqual(w.PolicyManagerOptions, n.PolicyManagerOptions) {
+		w.configurePending = true
+	}
+	w.ReTryPeriod = n.ReTryPeriod
+	w.BackoffOptions = n.BackoffOptions
+	w.AuthenticatorOptions = n.AuthenticatorOptions
+	w.PolicyManagerOptions = n.PolicyManagerOptions
+	for p := range w.backoffs {
+		w.backoffs[p] = backoff.New(*w.BackoffOptions)
+	}
+
+	klog.Infoln("applied the reloaded retry, backoff, auth and policy options, other changes need a restart")
+}
@@ -0,0 +1,65 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package worker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyReload(t *testing.T) {
+	reload := func(w *worker, change func(n *WorkerOptions)) {
+		n := NewWorkerOptions()
+		change(n)
+		w.reloads = make(chan *WorkerOptions, 1)
+		w.reloads <- n
+		w.applyReload()
+	}
+
+	t.Run("vault is configured again if the policy options changed", func(t *testing.T) {
+		w := newTestWorker(nil)
+		reload(w, func(n *WorkerOptions) { n.PolicyManagerOptions.Name = "vault-policy-controller" })
+		assert.True(t, w.configurePending)
+		assert.Equal(t, "vault-policy-controller", w.PolicyManagerOptions.Name)
+	})
+
+	t.Run("vault is configured again if the auth options changed", func(t *testing.T) {
+		w := newTestWorker(nil)
+		reload(w, func(n *WorkerOptions) { n.AuthenticatorOptions.Host = "https://kubernetes.default.svc" })
+		assert.True(t, w.configurePending)
+	})
+
+	t.Run("renaming the policy manager is rejected while the admin token is scoped", func(t *testing.T) {
+		w := newTestWorker(nil)
+		w.ScopeAdminToken = true
+		reload(w, func(n *WorkerOptions) {
+			n.ReTryPeriod *= 2
+			n.PolicyManagerOptions.Name = "vault-policy-controller"
+		})
+		assert.False(t, w.configurePending)
+		assert.Empty(t, w.PolicyManagerOptions.Name)
+		assert.Equal(t, RetryPeriod, w.ReTryPeriod)
+	})
+
+	t.Run("vault is not configured again for other changes", func(t *testing.T) {
+		w := newTestWorker(nil)
+		reload(w, func(n *WorkerOptions) { n.ReTryPeriod *= 2 })
+		assert.False(t, w.configurePending)
+		assert.Equal(t, 2*RetryPeriod, w.ReTryPeriod)
+	})
+}
@@ -0,0 +1,62 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package worker
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+)
+
+const serverShutdownTimeout = 5 * time.Second
+
+// startServer serves handler on the configured http address until the context
+// is cancelled. It returns right away, it only blocks to bind the address.
+func (o *WorkerOptions) startServer(ctx context.Context, handler http.Handler) error {
+	l, err := net.Listen("tcp", o.HTTPAddress)
+	if err != nil {
+		return errors.Wrapf(err, "failed to listen on %s", o.HTTPAddress)
+	}
+
+	srv := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), serverShutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			klog.Errorf("failed to shutdown the http server with %s", err.Error())
+		}
+	}()
+
+	go func() {
+		klog.Infof("serving metrics and health checks on %s", l.Addr())
+		if err := srv.Serve(l); err != nil && err != http.ErrServerClosed {
+			klog.Errorf("http server stopped with %s", err.Error())
+		}
+	}()
+
+	return nil
+}
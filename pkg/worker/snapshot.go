@@ -0,0 +1,236 @@
+/*
+Copyright AppsCode Inc. and Contributors
+
+Licensed under the AppsCode Community License 1.0.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://github.com/appscode/licenses/raw/1.0.0/AppsCode-Community-1.0.0.md
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package worker
+
+import (
+	"context"
+	"io"
+	"os"
+	"time"
+
+	"kubevault.dev/unsealer/pkg/events"
+	"kubevault.dev/unsealer/pkg/kv/backend"
+	"kubevault.dev/unsealer/pkg/snapshot"
+
+	vaultapi "github.com/hashicorp/vault/api"
+	"github.com/pkg/errors"
+	"github.com/robfig/cron/v3"
+	core "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// snapshotStore returns the store of the raft snapshots, the snapshots are
+// stored in chunks the backends of the mode accept
+func (w *worker) snapshotStore() *snapshot.Store {
+	chunkSize := w.SnapshotOptions.ChunkSize
+	if chunkSize == 0 {
+		if m, err := backend.Parse(w.Mode, w.BackendOptions); err == nil {
+			chunkSize = m.SnapshotChunkSize()
+		}
+	}
+	return snapshot.NewStore(w.keyStore, w.UnsealerOptions.KeyPrefix, chunkSize, w.SnapshotOptions.Retention)
+}
+
+// scheduleSnapshots takes raft snapshots on the schedule until ctx is done,
+// only the leader takes them. A snapshot that is still running when the next
+// one is due delays it.
+func (w *worker) scheduleSnapshots(ctx context.Context) error {
+	c := cron.New(cron.WithChain(cron.SkipIfStillRunning(cron.DiscardLogger)))
+	_, err := c.AddFunc(w.SnapshotOptions.Schedule, func() {
+		if !w.isLeader() {
+			return
+		}
+		err := w.takeSnapshot(ctx)
+		w.metrics.ObserveSnapshot(err)
+		if err != nil {
+			klog.Errorf("failed to take a raft snapshot with %s", err.Error())
+			w.recordFailure(events.ReasonSnapshotFailed, err)
+		}
+	})
+	if err != nil {
+		return errors.Wrap(err, "invalid snapshot schedule")
+	}
+
+	c.Start()
+	go func() {
+		<-ctx.Done()
+		<-c.Stop().Done()
+	}()
+	klog.Infof("raft snapshots are taken on the schedule %q", w.SnapshotOptions.Schedule)
+	return nil
+}
+
+// takeSnapshot streams a raft snapshot from the first unsealed vault server
+// to the snapshot store. It uses its own clients, the clients of the
+// reconcile loop are not shared.
+func (w *worker) takeSnapshot(ctx context.Context) error {
+	addresses, err := w.vaultAddresses(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to find the vault servers")
+	}
+
+	for _, address := range addresses {
+		n, err := w.newNode(address)
+		if err != nil {
+			return err
+		}
+		if sealed, err := n.unsealer.IsSealed(ctx); err != nil || sealed {
+			continue
+		}
+
+		klog.Infof("taking a raft snapshot of %s", address)
+		return w.withAdminToken(ctx, n, func(vc *vaultapi.Client) error {
+			// a snapshot takes longer than any other request, it is only
+			// bounded by ctx
+			sc, err := vc.Clone()
+			if err != nil {
+				return errors.Wrap(err, "failed to create vault api client")
+			}
+			sc.SetToken(vc.Token())
+			sc.SetClientTimeout(0)
+
+			m, err := w.snapshotStore().Save(ctx, func(out io.Writer) error {
+				if err := sc.Sys().RaftSnapshotWithContext(ctx, out); err != nil {
+					return errors.Wrap(err, "failed to take the raft snapshot")
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			klog.Infof("raft snapshot of %d bytes is stored in slot %d", m.Size, m.Slot)
+			w.recorder.Eventf(core.EventTypeNormal, events.ReasonSnapshotTaken, "raft snapshot of %d bytes is stored in slot %d", m.Size, m.Slot)
+			return nil
+		})
+	}
+	return errors.New("no vault server is unsealed")
+}
+
+// ListSnapshots returns the raft snapshots in the key store, the newest first
+func (o *WorkerOptions) ListSnapshots(ctx context.Context) ([]*snapshot.Manifest, error) {
+	w, err := o.newWorker()
+	if err != nil {
+		return nil, err
+	}
+	return w.snapshotStore().List(ctx)
+}
+
+// RestoreSnapshot restores a raft snapshot from the key store to a vault
+// cluster that is not initialized, the newest snapshot is restored if slot is
+// negative.
+//
+// The cluster is initialized with a throwaway key and root token first, vault
+// only accepts a snapshot once it is unsealed. The snapshot brings the keys
+// of the cluster it was taken from, so once it is restored the stored keys
+// unseal vault.
+func (o *WorkerOptions) RestoreSnapshot(ctx context.Context, slot int) error {
+	w, err := o.newWorker()
+	if err != nil {
+		return err
+	}
+
+	list, err := w.snapshotStore().List(ctx)
+	if err != nil {
+		return err
+	}
+	var m *snapshot.Manifest
+	for _, s := range list {
+		if slot < 0 || s.Slot == slot {
+			m = s
+			break
+		}
+	}
+	if m == nil {
+		return errors.New("no snapshot found in the key store")
+	}
+
+	nodes, err := w.vaultNodes(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to find the vault servers")
+	}
+	vc := nodes[0].vc
+	if initialized, err := vc.Sys().InitStatusWithContext(ctx); err != nil {
+		return errors.Wrap(err, "failed to check the init status")
+	} else if initialized {
+		return errors.Errorf("vault %s is initialized, a snapshot is only restored to an empty cluster", nodes[0].address)
+	}
+
+	// the snapshot is verified before vault gets to see any of it
+	f, err := os.CreateTemp("", "vault-snapshot-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) //nolint:errcheck
+	defer f.Close()           //nolint:errcheck
+	if err := w.snapshotStore().Load(ctx, m, f); err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	klog.Infof("initializing vault %s with a throwaway key to restore the snapshot", nodes[0].address)
+	if err := bootstrapVault(ctx, vc); err != nil {
+		return err
+	}
+
+	klog.Infof("restoring the snapshot of %s from slot %d", m.Time.Format(time.RFC3339), m.Slot)
+	if err := vc.Sys().RaftSnapshotRestoreWithContext(ctx, f, true); err != nil {
+		return errors.Wrap(err, "failed to restore the raft snapshot")
+	}
+
+	klog.Infoln("snapshot is restored, vault is unsealed with the stored keys by the unsealer from now on")
+	return nil
+}
+
+// bootstrapVault initializes vault with a single key that is not stored,
+// unseals it and sets the root token on the client.
+func bootstrapVault(ctx context.Context, vc *vaultapi.Client) error {
+	status, err := vc.Sys().SealStatusWithContext(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to check seal status")
+	}
+
+	req := &vaultapi.InitRequest{SecretShares: 1, SecretThreshold: 1}
+	if status.Type != "shamir" {
+		req = &vaultapi.InitRequest{RecoveryShares: 1, RecoveryThreshold: 1}
+	}
+	resp, err := vc.Sys().InitWithContext(ctx, req)
+	if err != nil {
+		return errors.Wrap(err, "failed to initialize vault")
+	}
+	for _, key := range resp.Keys {
+		if _, err := vc.Sys().UnsealWithContext(ctx, key); err != nil {
+			return errors.Wrap(err, "failed to unseal vault")
+		}
+	}
+	vc.SetToken(resp.RootToken)
+
+	// the restore is only accepted by the active server
+	for {
+		leader, err := vc.Sys().LeaderWithContext(ctx)
+		if err == nil && leader.IsSelf {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "vault did not become active")
+		case <-time.After(time.Second):
+		}
+	}
+}
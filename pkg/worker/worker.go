@@ -17,16 +17,18 @@ limitations under the License.
 package worker
 
 import (
+	"context"
+	"net/http"
+	"sync"
 	"time"
 
+	"kubevault.dev/unsealer/pkg/backoff"
+	"kubevault.dev/unsealer/pkg/events"
 	"kubevault.dev/unsealer/pkg/kv"
-	"kubevault.dev/unsealer/pkg/kv/aws_kms"
-	"kubevault.dev/unsealer/pkg/kv/aws_ssm"
-	"kubevault.dev/unsealer/pkg/kv/azure"
-	"kubevault.dev/unsealer/pkg/kv/cloudkms"
-	"kubevault.dev/unsealer/pkg/kv/gcs"
-	"kubevault.dev/unsealer/pkg/kv/kubernetes"
-	"kubevault.dev/unsealer/pkg/vault"
+	"kubevault.dev/unsealer/pkg/kv/backend"
+	"kubevault.dev/unsealer/pkg/kv/placement"
+	"kubevault.dev/unsealer/pkg/leader"
+	"kubevault.dev/unsealer/pkg/metrics"
 	"kubevault.dev/unsealer/pkg/vault/auth"
 	"kubevault.dev/unsealer/pkg/vault/policy"
 	"kubevault.dev/unsealer/pkg/vault/unseal"
@@ -34,122 +36,580 @@ import (
 
 	vaultapi "github.com/hashicorp/vault/api"
 	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+	core "k8s.io/api/core/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	kubeclient "k8s.io/client-go/kubernetes"
 	"k8s.io/klog/v2"
 )
 
-func (o *WorkerOptions) Run() error {
-	keyStore, err := o.getKVService()
+type phase string
+
+const (
+	phaseInit      phase = "init"
+	phaseUnseal    phase = "unseal"
+	phaseConfigure phase = "configure"
+)
+
+// worker holds the state that is carried from one reconcile pass to the next
+type worker struct {
+	*WorkerOptions
+
+	keyStore    kv.Service
+	rootTokenID string
+	metrics     *metrics.Metrics
+	health      *health
+	recorder    events.Recorder
+	// nil unless leader election, events or service discovery is enabled
+	kubeClient kubeclient.Interface
+
+	// the vault servers of the last pass by address
+	nodes map[string]*node
+
+	// nil if leader election is disabled
+	elector *leader.Elector
+	// the last pass was made as leader
+	wasLeader bool
+
+	// vault was unsealed by this worker, but it is not configured yet
+	configurePending bool
+
+	// the stored admin token is not looked up again before this time
+	adminTokenRenewAt time.Time
+	// ID of the root token that is kept in the key store until it is revoked
+	archivedRootTokenID string
+	// vault runs one root token generation at a time, the snapshot cron and
+	// the reconcile loop must not cancel each other's
+	generateRootMu sync.Mutex
+
+	// every phase gets its own retry budget
+	backoffs map[phase]*backoff.Backoff
+
+	// options reloaded from the config file, nil if it is not watched
+	reloads chan *WorkerOptions
+}
+
+// result reports what a reconcile pass did
+type result struct {
+	// the phase that failed, empty if the pass succeeded
+	phase phase
+	// vault was initialized in this pass
+	initialized bool
+	// a vault server was unsealed in this pass
+	unsealed bool
+	// state of every vault server by address
+	nodes map[string]string
+}
+
+// errVaultUnreachable is returned when the status of vault could not be read
+var errVaultUnreachable = errors.New("vault is unreachable")
+
+func (o *WorkerOptions) Run(ctx context.Context) error {
+	w, err := o.newWorker()
 	if err != nil {
-		return errors.Wrap(err, "failed to create kv service")
+		return err
 	}
+	// the lease and the event recorder are kept until the last action is
+	// finished, not just until the shutdown signal arrives
+	bgCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
 
-	vc, err := vault.NewVaultClient(o.Address, o.InsecureSkipTLSVerify, []byte(o.CaCert))
-	if err != nil {
-		return errors.Wrap(err, "failed to create vault api client")
+	if o.EventOptions.Enabled {
+		w.recorder = events.NewRecorder(bgCtx, w.kubeClient, o.EventOptions)
+	}
+
+	if o.LeaderElectionOptions.Enabled {
+		leaseName := o.LeaderElectionOptions.LeaseName
+		if leaseName == "" {
+			leaseName = o.UnsealerOptions.ClusterName + "-vault-unsealer"
+		}
+
+		w.elector, err = leader.Start(bgCtx, w.kubeClient, o.LeaderElectionOptions, leaseName)
+		if err != nil {
+			return err
+		}
+	}
+
+	if o.config != nil && o.config.Path() != "" {
+		if err := w.watchConfig(bgCtx); err != nil {
+			return err
+		}
+	}
+
+	if o.SnapshotOptions.Schedule != "" {
+		if err := w.scheduleSnapshots(bgCtx); err != nil {
+			return err
+		}
 	}
 
-	o.unsealAndConfigureVault(vc, keyStore, o.ReTryPeriod)
+	if o.HTTPAddress != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", w.metrics.Handler())
+		mux.HandleFunc("/healthz", w.health.healthz)
+		mux.HandleFunc("/readyz", w.health.readyz)
+		if err := o.startServer(ctx, mux); err != nil {
+			return err
+		}
+	}
+
+	if err := w.unsealAndConfigureVault(ctx); err != nil {
+		return err
+	}
 
+	klog.Infoln("received shutdown signal, vault unsealer stopped")
 	return nil
 }
 
-// It will do:
-//   - If vault is not initialized, then initialize vault
-//   - If vault is not unsealed, then unseal it
-//   - configure vault
-//
-// it will periodically check for infinite time
-func (o *WorkerOptions) unsealAndConfigureVault(vc *vaultapi.Client, keyStore kv.Service, retryPeriod time.Duration) {
-	rootTokenID := util.RootTokenID(o.UnsealerOptions.KeyPrefix)
-
-	unsealer, err := unseal.New(keyStore, vc, *o.UnsealerOptions)
+// newWorker creates the key store and the kubernetes client, the vault
+// clients are created when the vault servers are known.
+func (o *WorkerOptions) newWorker() (*worker, error) {
+	m := metrics.New(o.UnsealerOptions.ClusterName)
+	keyStore, err := o.getKVService(m)
 	if err != nil {
-		klog.Errorf("failed to create the unsealer client with %s", err.Error())
+		return nil, errors.Wrap(err, "failed to create kv service")
 	}
 
+	var kubeClient kubeclient.Interface
+	if o.LeaderElectionOptions.Enabled || o.EventOptions.Enabled || o.VaultService != "" {
+		if kubeClient, err = newKubeClient(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &worker{
+		WorkerOptions:       o,
+		keyStore:            keyStore,
+		kubeClient:          kubeClient,
+		rootTokenID:         util.RootTokenID(o.UnsealerOptions.KeyPrefix),
+		archivedRootTokenID: util.ArchivedRootTokenID(o.UnsealerOptions.KeyPrefix),
+		metrics:             m,
+		health:              newHealth(o.LivenessWindow),
+		recorder:            events.NewNopRecorder(),
+		backoffs: map[phase]*backoff.Backoff{
+			phaseInit:      backoff.New(*o.BackoffOptions),
+			phaseUnseal:    backoff.New(*o.BackoffOptions),
+			phaseConfigure: backoff.New(*o.BackoffOptions),
+		},
+	}, nil
+}
+
+// unsealAndConfigureVault runs reconcile passes until the context is
+// cancelled. Healthy passes are repeated every retry period, a failed phase is
+// retried with exponential backoff until its retry budget runs out.
+//
+// Once the context is cancelled no new action is started, an action that is
+// already running is allowed to finish. It returns an error when a retry
+// budget is exhausted, or when the vault is left in a state that can not be
+// recovered by retrying.
+func (w *worker) unsealAndConfigureVault(ctx context.Context) error {
 	period := time.Second
 
 	for {
-		time.Sleep(period)
-		period = retryPeriod
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(period):
+		}
 
-		klog.Info("checking if the vault is initialized or not.")
+		w.applyReload()
 
-		initialized, err := unsealer.IsInitialized()
-		if err != nil {
-			klog.Errorf("failed to get the initialized status with %s", err.Error())
+		res, err := w.reconcile(ctx)
+		w.health.observe(err, res.nodes)
+		if err == nil {
+			for _, b := range w.backoffs {
+				b.Reset()
+			}
+			period = w.ReTryPeriod
 			continue
 		}
+		if errors.Is(err, unseal.ErrKeysNotStored) {
+			return errors.Wrap(err, "failed to initialize the vault")
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
 
-		// the vault is not initialized, check the read/write access & try to initialize the vault
-		if !initialized {
-			klog.Info("trying to initialize the vault")
+		klog.Errorf("%s phase failed with %s", res.phase, err.Error())
 
-			if err = unsealer.CheckReadWriteAccess(); err != nil {
-				klog.Errorf("failed to check the read/write access to the key store with %s", err.Error())
-				continue
-			}
+		period, err = w.backoffs[res.phase].Next()
+		if err != nil {
+			return errors.Wrapf(err, "giving up on the %s phase", res.phase)
+		}
+		klog.Infof("retrying the %s phase in %s", res.phase, period)
+	}
+}
 
-			// try to Initialize the vault
-			if err = unsealer.Init(); err != nil {
-				klog.Errorf("failed to initialize the vault with %s", err.Error())
-				continue
-			}
+// reconcile makes a single pass over the vault servers, it will do:
+//   - If no vault server is initialized, then initialize one of them
+//   - If vault servers are not unsealed, then unseal them concurrently
+//   - configure vault, after it is unsealed
+//
+// With leader election only the leader initializes and configures vault,
+// non-leaders only unseal it or stand by.
+//
+// On failure the result holds the phase that failed.
+func (w *worker) reconcile(ctx context.Context) (res result, err error) {
+	leading := w.isLeader()
+	if w.elector != nil {
+		// a new leader does not know whether the previous one finished
+		// configuring vault
+		if leading && !w.wasLeader {
+			w.configurePending = true
+		}
+		w.wasLeader = leading
+
+		if !leading && w.LeaderElectionOptions.StandBy {
+			klog.Infoln("not the leader, standing by")
+			return res, nil
+		}
+	}
+
+	nodes, err := w.vaultNodes(ctx)
+	if err != nil {
+		return result{phase: phaseInit}, errors.Wrap(err, "failed to find the vault servers")
+	}
+
+	klog.Info("checking if the vault is initialized or not.")
+
+	statuses := w.checkNodes(ctx, nodes)
+	defer func() {
+		res.nodes = make(map[string]string, len(statuses))
+		for _, s := range statuses {
+			res.nodes[s.address] = s.state()
+		}
+	}()
+
+	initialized, reachable := false, true
+	for _, s := range statuses {
+		initialized = initialized || s.initialized
+		reachable = reachable && s.err == nil
+	}
+
+	// no vault server is initialized, check the read/write access & try to
+	// initialize one of them. The others join it or share its storage.
+	if !initialized {
+		// an unreachable server might be initialized already
+		if !reachable {
+			res.phase = phaseInit
+			return res, nodeErrors(statuses)
+		}
+		if !leading {
+			klog.Infoln("vault is not initialized, waiting for the leader to initialize it")
+			return res, nil
 		}
 
-		klog.Infof("vault must be initialized here, initialized value: %v", initialized)
-		klog.Infoln("checking if the vault is sealed or not")
+		s := statuses[0]
+		klog.Infof("trying to initialize the vault server %s", s.address)
 
-		// checking the sealed status of the vault
-		sealed, err := unsealer.IsSealed()
+		if err = s.unsealer.CheckReadWriteAccess(ctx); err != nil {
+			w.recordFailure(events.ReasonKeyStoreUnreachable, err)
+			res.phase = phaseInit
+			return res, errors.Wrap(err, "failed to check the read/write access to the key store")
+		}
+
+		// try to Initialize the vault
+		err = s.unsealer.Init(ctx)
+		w.metrics.ObserveInit(err)
 		if err != nil {
-			klog.Errorf("failed to get the sealed status with %s", err.Error())
-			continue
+			w.recordFailure(events.ReasonVaultInitFailed, err)
+			res.phase = phaseInit
+			return res, errors.Wrap(err, "failed to initialize the vault")
+		}
+		w.recorder.Eventf(core.EventTypeNormal, events.ReasonVaultInitialized, "vault server %s is initialized and its keys are stored in the key store", s.address)
+		res.initialized = true
+		s.initialized, s.sealed = true, true
+
+		// servers that share the storage of the initialized server are
+		// initialized now too, they must not join a raft cluster
+		if len(statuses) > 1 {
+			others := make([]*node, 0, len(statuses)-1)
+			for _, o := range statuses[1:] {
+				others = append(others, o.node)
+			}
+			copy(statuses[1:], w.checkNodes(ctx, others))
 		}
+	}
 
-		if !sealed {
-			klog.Infoln("vault is unsealed")
-			continue
+	klog.Infoln("vault must be initialized here, checking if the vault servers are sealed or not")
+
+	var sealed, joining []*nodeStatus
+	for _, s := range statuses {
+		switch {
+		case s.err != nil:
+		case !s.initialized:
+			klog.Infof("vault server %s is not initialized, it has to join the raft cluster", s.address)
+			joining = append(joining, s)
+		case s.sealed:
+			sealed = append(sealed, s)
+		default:
+			klog.Infof("vault server %s is unsealed", s.address)
 		}
+	}
 
+	if len(sealed) > 0 {
 		klog.Infoln("making the unseal vault request")
 
-		if err := unsealer.Unseal(); err != nil {
-			klog.Errorf("failed to unseal the vault with %s", err.Error())
-			continue
+		if w.unsealNodes(ctx, sealed) {
+			res.unsealed = true
+			w.configurePending = w.configurePending || leading
 		}
+	}
 
-		for {
-			klog.Infoln("trying to configure the vault")
+	// the servers that join the raft cluster share the keys of the cluster,
+	// they are unsealed right away
+	if len(joining) > 0 && leading {
+		if joined := w.joinNodes(ctx, joining, statuses); len(joined) > 0 {
+			klog.Infoln("making the unseal vault request for the joined servers")
+			res.unsealed = w.unsealNodes(ctx, joined) || res.unsealed
+		}
+	}
 
-			err := o.configureVault(vc, keyStore, rootTokenID)
-			if err == nil {
-				klog.Infoln("vault is configured")
+	var target *nodeStatus
+	if leading {
+		for _, s := range statuses {
+			if s.err == nil && s.initialized && !s.sealed {
+				target = s
 				break
 			}
+		}
+	}
+
+	if target != nil {
+		// the unsealer may have stopped after vault migrated its seal, but
+		// before the unseal keys were moved
+		if err := target.unsealer.FinishSealMigration(ctx); err != nil {
+			res.phase = phaseUnseal
+			return res, errors.Wrap(err, "failed to finish the seal migration")
+		}
+
+		if w.configurePending {
+			klog.Infof("trying to configure the vault through %s", target.address)
+
+			if err := w.configure(ctx, target.node); err != nil {
+				w.metrics.ObserveConfigureFailure()
+				w.recordFailure(events.ReasonVaultConfigFailed, err)
+				res.phase = phaseConfigure
+				return res, errors.Wrap(err, "failed to configure the vault")
+			}
+			w.recorder.Event(core.EventTypeNormal, events.ReasonVaultConfigured, "vault kubernetes auth and policies are configured")
+			w.configurePending = false
+
+			klog.Infoln("vault is configured")
+		} else if w.ScopeAdminToken && !w.GenerateRootToken {
+			if err := w.renewAdminToken(ctx, target.node); err != nil {
+				res.phase = phaseConfigure
+				return res, err
+			}
+		}
+	}
 
-			klog.Errorf("failed to configure the vault with %s", err.Error())
+	if err := nodeErrors(statuses); err != nil {
+		for _, s := range statuses {
+			if s.err != nil {
+				res.phase = s.phase
+				break
+			}
 		}
+		return res, err
 	}
+	return res, nil
 }
 
-// configureVault will do:
-//   - enable and configure kubernetes auth
-//   - create policy and policy binding
-func (o *WorkerOptions) configureVault(vc *vaultapi.Client, keyStore kv.Service, rootTokenID string) error {
-	rootToken, err := keyStore.Get(rootTokenID)
+// checkNodes reads the initialized and sealed status of the vault servers,
+// at most Concurrency of them at a time.
+func (w *worker) checkNodes(ctx context.Context, nodes []*node) []*nodeStatus {
+	statuses := make([]*nodeStatus, len(nodes))
+
+	var g errgroup.Group
+	g.SetLimit(w.Concurrency)
+	for i, n := range nodes {
+		s := &nodeStatus{node: n}
+		statuses[i] = s
+
+		g.Go(func() error {
+			initialized, err := s.unsealer.IsInitialized(ctx)
+			if err != nil {
+				s.phase = phaseInit
+				s.err = errors.Wrapf(errVaultUnreachable, "failed to get the initialized status with %s", err.Error())
+				w.metrics.ObserveUp(s.address, false)
+				return nil
+			}
+			w.metrics.ObserveUp(s.address, true)
+			s.initialized = initialized
+			if !initialized {
+				return nil
+			}
+
+			sealed, err := s.unsealer.IsSealed(ctx)
+			if err != nil {
+				s.phase = phaseUnseal
+				s.err = errors.Wrapf(errVaultUnreachable, "failed to get the sealed status with %s", err.Error())
+				return nil
+			}
+			s.sealed = sealed
+			w.metrics.ObserveSealed(s.address, sealed)
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	return statuses
+}
+
+// unsealNodes unseals the vault servers, at most Concurrency of them at a
+// time. It returns true if at least one of them was unsealed.
+func (w *worker) unsealNodes(ctx context.Context, statuses []*nodeStatus) bool {
+	var g errgroup.Group
+	g.SetLimit(w.Concurrency)
+	for _, s := range statuses {
+		g.Go(func() error {
+			err := s.unsealer.Unseal(ctx)
+			w.metrics.ObserveUnseal(s.address, err)
+			if err != nil {
+				s.phase = phaseUnseal
+				s.err = errors.Wrap(err, "failed to unseal the vault")
+				w.recordFailure(events.ReasonVaultUnsealFailed, errors.Wrapf(err, "vault server %s", s.address))
+				return nil
+			}
+			w.recorder.Eventf(core.EventTypeNormal, events.ReasonVaultUnsealed, "vault server %s is unsealed", s.address)
+			w.metrics.ObserveSealed(s.address, false)
+			s.sealed = false
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	for _, s := range statuses {
+		if !s.sealed {
+			return true
+		}
+	}
+	return false
+}
+
+// joinNodes joins the vault servers to the raft cluster, at most Concurrency
+// of them at a time. It returns the servers that joined.
+func (w *worker) joinNodes(ctx context.Context, joining, statuses []*nodeStatus) []*nodeStatus {
+	leader := w.RaftOptions.LeaderAPIAddress
+	if leader == "" {
+		for _, s := range statuses {
+			if s.err == nil && s.initialized && !s.sealed {
+				leader = s.address
+				break
+			}
+		}
+	}
+	if leader == "" {
+		klog.Infoln("no vault server is unsealed, the raft cluster is joined once one is")
+		return nil
+	}
+
+	var g errgroup.Group
+	g.SetLimit(w.Concurrency)
+	for _, s := range joining {
+		g.Go(func() error {
+			if err := s.unsealer.JoinRaft(ctx, leader, w.RaftOptions.LeaderCACert); err != nil {
+				s.phase = phaseUnseal
+				s.err = err
+				w.recordFailure(events.ReasonVaultJoinFailed, errors.Wrapf(err, "vault server %s", s.address))
+				return nil
+			}
+			w.recorder.Eventf(core.EventTypeNormal, events.ReasonVaultJoined, "vault server %s joined the raft cluster of %s", s.address, leader)
+			s.initialized, s.sealed = true, true
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	var joined []*nodeStatus
+	for _, s := range joining {
+		if s.err == nil {
+			joined = append(joined, s)
+		}
+	}
+	return joined
+}
+
+// nodeErrors returns the errors of the vault servers, an error of a single
+// server is returned as is.
+func nodeErrors(statuses []*nodeStatus) error {
+	var errs []error
+	for _, s := range statuses {
+		if s.err != nil {
+			errs = append(errs, errors.Wrapf(s.err, "vault server %s", s.address))
+		}
+	}
+	if len(statuses) == 1 && len(errs) == 1 {
+		return statuses[0].err
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// configure configures vault through the given server. A stored root token
+// is replaced by a scoped admin token once vault is configured.
+func (w *worker) configure(ctx context.Context, n *node) error {
+	return w.withAdminToken(ctx, n, func(vc *vaultapi.Client) error {
+		if err := w.configureVault(ctx, vc); err != nil {
+			return err
+		}
+		if w.ScopeAdminToken && !w.GenerateRootToken {
+			return w.scopeAdminToken(ctx, vc)
+		}
+		return nil
+	})
+}
+
+// withAdminToken calls fn with a client of the vault server that uses the
+// admin token. The token is read from the key store, or generated and revoked
+// once fn returns.
+func (w *worker) withAdminToken(ctx context.Context, n *node, fn func(vc *vaultapi.Client) error) error {
+	var token []byte
+	var err error
+	if !w.GenerateRootToken {
+		if token, err = w.keyStore.Get(ctx, w.rootTokenID); err != nil {
+			return errors.Wrap(err, "failed to get the root token")
+		}
+	}
+
+	// the client of the server is shared, it does not get the token
+	vc, err := n.vc.Clone()
 	if err != nil {
-		return errors.Wrap(err, "failed to get the root token")
+		return errors.Wrap(err, "failed to create vault api client")
+	}
+	if !w.GenerateRootToken {
+		vc.SetToken(string(token))
+		return fn(vc)
 	}
 
-	// set the rootToken
-	vc.SetToken(string(rootToken))
+	w.generateRootMu.Lock()
+	klog.Infoln("generating a root token")
+	rootToken, err := n.unsealer.GenerateRootToken(ctx)
+	w.generateRootMu.Unlock()
+	if err != nil {
+		return errors.Wrap(err, "failed to generate a root token")
+	}
+	vc.SetToken(rootToken)
+	defer func() {
+		// the token must not outlive its use, not even on shutdown
+		if err := vc.Auth().Token().RevokeSelfWithContext(context.WithoutCancel(ctx), ""); err != nil {
+			klog.Errorf("failed to revoke the generated root token with %s", err.Error())
+		} else {
+			klog.Infoln("generated root token is revoked")
+		}
+	}()
+
+	return fn(vc)
+}
 
+// configureVault will do:
+//   - enable and configure kubernetes auth
+//   - create policy and policy binding
+func (o *WorkerOptions) configureVault(ctx context.Context, vc *vaultapi.Client) error {
 	k8sAuth := auth.NewKubernetesAuthenticator(vc, o.AuthenticatorOptions)
 
 	klog.Infoln("enable kubernetes auth")
 
-	err = k8sAuth.EnsureAuth()
+	err := k8sAuth.EnsureAuth(ctx)
 	if err != nil {
 		return errors.Wrap(err, "failed to enable kubernetes auth")
 	}
@@ -158,7 +618,7 @@ func (o *WorkerOptions) configureVault(vc *vaultapi.Client, keyStore kv.Service,
 
 	klog.Infoln("configure kubernetes auth")
 
-	err = k8sAuth.ConfigureAuth()
+	err = k8sAuth.ConfigureAuth(ctx)
 	if err != nil {
 		return errors.Wrap(err, "failed to configure kubernetes auth")
 	}
@@ -167,7 +627,7 @@ func (o *WorkerOptions) configureVault(vc *vaultapi.Client, keyStore kv.Service,
 
 	klog.Infoln("write policy and policy binding for policy controller")
 
-	err = policy.EnsurePolicyAndPolicyBinding(vc, o.PolicyManagerOptions)
+	err = policy.EnsurePolicyAndPolicyBinding(ctx, vc, o.PolicyManagerOptions)
 	if err != nil {
 		return errors.Wrap(err, "failed to write policy and policy binding for policy controller")
 	}
@@ -177,57 +637,59 @@ func (o *WorkerOptions) configureVault(vc *vaultapi.Client, keyStore kv.Service,
 	return nil
 }
 
-func (o *WorkerOptions) getKVService() (kv.Service, error) {
-	switch o.Mode {
-	case ModeAwsKmsSsm:
-		ssmService, err := aws_ssm.New(o.AwsOptions.UseSecureString, o.AwsOptions.SsmKeyPrefix)
-		if err != nil {
-			return nil, errors.Wrap(err, "failed to create aws ssm service")
-		}
-
-		var kvService kv.Service
-
-		if o.AwsOptions.UseSecureString {
-			kvService = ssmService
-		} else {
-			kvService, err = aws_kms.New(ssmService, o.AwsOptions.KmsKeyID)
-			if err != nil {
-				return nil, errors.Wrap(err, "failed to create kv service for aws")
-			}
-		}
-
-		return kvService, nil
-
-	case ModeGoogleCloudKmsGCS:
-		gcsService, err := gcs.New(o.GoogleOptions.StorageBucket, o.GoogleOptions.StoragePrefix)
-		if err != nil {
-			return nil, errors.Wrap(err, "failed to create google gcs service")
-		}
+// getKVService returns the store of the selected mode, or a store that places
+// the shares in the stores of several modes if a share placement is given.
+// The calls to every store are recorded under the mode of that store.
+func (o *WorkerOptions) getKVService(m *metrics.Metrics) (kv.Service, error) {
+	primary, err := o.newKVService(m, o.Mode)
+	if err != nil || len(o.SharePlacement) == 0 {
+		return primary, err
+	}
 
-		kvService, err := cloudkms.New(gcsService, o.GoogleOptions.KmsProject, o.GoogleOptions.KmsLocation, o.GoogleOptions.KmsKeyRing, o.GoogleOptions.KmsCryptoKey)
-		if err != nil {
-			return nil, errors.Wrap(err, "failed to create kv service for aws")
+	rules, err := placement.ParseRules(o.SharePlacement)
+	if err != nil {
+		return nil, err
+	}
+	stores := map[string]kv.Service{o.Mode: primary}
+	for _, rule := range rules {
+		if _, ok := stores[rule.Mode]; ok {
+			continue
 		}
-
-		return kvService, nil
-
-	case ModeAzureKeyVault:
-		kvService, err := azure.NewKVService(o.AzureOptions)
-		if err != nil {
-			return nil, errors.Wrap(err, "failed to create azure kv service")
+		if stores[rule.Mode], err = o.newKVService(m, rule.Mode); err != nil {
+			return nil, err
 		}
+	}
+	return placement.New(primary, rules, stores)
+}
 
-		return kvService, nil
-
-	case ModeKubernetesSecret:
-		kvService, err := kubernetes.NewKVService(o.KubernetesOptions)
-		if err != nil {
-			return nil, errors.Wrap(err, "failed to create kv service for kubernetes")
-		}
+// newKVService returns the store of a registered mode, with the key store
+// timeout and metrics labeled with the mode
+func (o *WorkerOptions) newKVService(m *metrics.Metrics, mode string) (kv.Service, error) {
+	bm, err := backend.Parse(mode, o.BackendOptions)
+	if err != nil {
+		return nil, err
+	}
+	store, err := bm.New(o.BackendOptions)
+	if err != nil {
+		return nil, err
+	}
+	return m.InstrumentKV(kv.WithTimeout(store, o.KeyStoreTimeout), mode), nil
+}
 
-		return kvService, nil
+// isLeader returns true if this worker is allowed to initialize and configure
+// vault, which is always the case without leader election.
+func (w *worker) isLeader() bool {
+	return w.elector == nil || w.elector.IsLeader()
+}
 
-	default:
-		return nil, errors.Errorf("failed to create unkown mode %q", o.Mode)
+// recordFailure records a warning event, errors caused by the key store are
+// reported with their own reasons.
+func (w *worker) recordFailure(reason string, err error) {
+	switch {
+	case errors.Is(err, unseal.ErrKeyExists):
+		reason = events.ReasonKeysAlreadyExist
+	case errors.Is(err, unseal.ErrKeyStoreUnreachable):
+		reason = events.ReasonKeyStoreUnreachable
 	}
+	w.recorder.Event(core.EventTypeWarning, reason, err.Error())
 }